@@ -0,0 +1,131 @@
+package broadcast
+
+import (
+	"sync"
+
+	"github.com/golang/geo/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+var DroppedCounter = promauto.NewCounter(
+	prometheus.CounterOpts{
+		Namespace: "geottn",
+		Name:      "broadcast_dropped_total",
+		Help:      "The total number of points dropped for slow subscribers",
+	},
+)
+
+// Broadcaster dispatches the newly stored points to its subscribers
+type Broadcaster struct {
+	mu         sync.RWMutex
+	subs       map[*Subscription]struct{}
+	bufferSize int
+}
+
+// Filter selects the points sent to a subscriber, an empty filter matches everything
+type Filter struct {
+	// Keys only matches these devices
+	Keys []string
+
+	// Region only matches the points inside
+	Region s2.Region
+}
+
+// Subscription receives the points matching its filter on C until closed
+type Subscription struct {
+	C <-chan storage.DataPoint
+
+	c      chan storage.DataPoint
+	b      *Broadcaster
+	keys   map[string]struct{}
+	region s2.Region
+}
+
+// New returns a Broadcaster, bufferSize is the number of points queued for each subscriber
+func New(bufferSize int) *Broadcaster {
+	return &Broadcaster{
+		subs:       make(map[*Subscription]struct{}),
+		bufferSize: bufferSize,
+	}
+}
+
+// Subscribe returns a new Subscription receiving the points matching f
+func (b *Broadcaster) Subscribe(f Filter) *Subscription {
+	c := make(chan storage.DataPoint, b.bufferSize)
+	s := &Subscription{
+		C:      c,
+		c:      c,
+		b:      b,
+		region: f.Region,
+	}
+	if len(f.Keys) > 0 {
+		s.keys = make(map[string]struct{}, len(f.Keys))
+		for _, k := range f.Keys {
+			s.keys[k] = struct{}{}
+		}
+	}
+
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+// Publish sends dp to the matching subscribers, it never blocks,
+// when a subscriber is too slow its oldest queued point is dropped to make room
+func (b *Broadcaster) Publish(dp storage.DataPoint) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for s := range b.subs {
+		if !s.match(&dp) {
+			continue
+		}
+
+		select {
+		case s.c <- dp:
+			continue
+		default:
+		}
+
+		// queue is full, drop the oldest
+		select {
+		case <-s.c:
+			DroppedCounter.Inc()
+		default:
+		}
+		select {
+		case s.c <- dp:
+		default:
+			DroppedCounter.Inc()
+		}
+	}
+}
+
+// Close unsubscribes, C is closed
+func (s *Subscription) Close() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+
+	if _, ok := s.b.subs[s]; !ok {
+		return
+	}
+	delete(s.b.subs, s)
+	close(s.c)
+}
+
+func (s *Subscription) match(dp *storage.DataPoint) bool {
+	if s.keys != nil {
+		if _, ok := s.keys[dp.Key]; !ok {
+			return false
+		}
+	}
+	if s.region != nil {
+		return s.region.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(dp.Lat, dp.Lng)))
+	}
+	return true
+}
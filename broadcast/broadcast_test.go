@@ -0,0 +1,46 @@
+package broadcast
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+func TestBroadcaster(t *testing.T) {
+	b := New(2)
+
+	all := b.Subscribe(Filter{})
+	keys := b.Subscribe(Filter{Keys: []string{"KEY"}})
+	rect := s2.RectFromLatLng(s2.LatLngFromDegrees(48.0, 2.0))
+	rect = rect.AddPoint(s2.LatLngFromDegrees(49.0, 3.0))
+	region := b.Subscribe(Filter{Region: rect})
+
+	b.Publish(storage.DataPoint{Key: "KEY", Lat: 48.5, Lng: 2.5})
+	b.Publish(storage.DataPoint{Key: "KEY2", Lat: 40.5, Lng: 2.5})
+
+	require.Len(t, all.C, 2)
+	require.Len(t, keys.C, 1)
+	require.Len(t, region.C, 1)
+	dp := <-region.C
+	require.Equal(t, "KEY", dp.Key)
+
+	// slow subscriber keeps the most recent points
+	b.Publish(storage.DataPoint{Key: "KEY3", Lat: 48.5, Lng: 2.5})
+	require.Len(t, all.C, 2)
+	dp = <-all.C
+	require.Equal(t, "KEY2", dp.Key)
+	dp = <-all.C
+	require.Equal(t, "KEY3", dp.Key)
+
+	all.Close()
+	_, ok := <-all.C
+	require.False(t, ok)
+	// closing twice is fine
+	all.Close()
+
+	b.Publish(storage.DataPoint{Key: "KEY", Lat: 48.5, Lng: 2.5})
+	require.Len(t, keys.C, 2)
+}
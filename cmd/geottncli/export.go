@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	kitlog "github.com/go-kit/kit/log"
+	"google.golang.org/grpc"
+
+	"github.com/akhenakh/geottn/export"
+	"github.com/akhenakh/geottn/geottnsvc"
+)
+
+// exportCmd writes the history of a device in an export format
+// eg: geottncli export -key ttgosens00 -format gpx -out ttgosens00.gpx
+func exportCmd(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	uri := fs.String("geoTTNURI", "localhost:9200", "geoTTN grpc URI")
+	key := fs.String("key", "", "the device to export")
+	format := fs.String("format", "gpx", "export format: "+strings.Join(export.Formats, ", "))
+	out := fs.String("out", "", "output file, stdout if empty")
+	pageSize := fs.Int("pageSize", 500, "number of points queried at once")
+	_ = fs.Parse(args)
+
+	if *key == "" {
+		log.Fatal("key is required")
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	enc, err := export.NewEncoder(*format, w, *key, kitlog.NewLogfmtLogger(os.Stderr))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conn, err := grpc.Dial(*uri, grpc.WithInsecure())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	c := geottnsvc.NewGeoTTNClient(conn)
+	ctx := context.Background()
+
+	var cursor []byte
+	for {
+		rep, err := c.GetAll(ctx, &geottnsvc.GetAllRequest{
+			Key:         *key,
+			Count:       int32(*pageSize),
+			Cursor:      cursor,
+			OldestFirst: true,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		for _, p := range rep.Points {
+			if err := enc.Encode(geottnsvc.DataPointToStorage(p)); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		if rep.NextCursor == nil {
+			break
+		}
+		cursor = rep.NextCursor
+	}
+
+	if err := enc.Close(); err != nil {
+		log.Fatal(err)
+	}
+}
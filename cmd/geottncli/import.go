@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/golang/protobuf/ptypes"
+	"google.golang.org/grpc"
+
+	"github.com/akhenakh/geottn/geottnsvc"
+	"github.com/akhenakh/geottn/importer"
+	"github.com/akhenakh/geottn/storage"
+)
+
+// importCmd streams the points read from files to the server
+// eg: geottncli import -key ttgosens00 history.gpx
+func importCmd(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	uri := fs.String("geoTTNURI", "localhost:9200", "geoTTN grpc URI")
+	key := fs.String("key", "", "the device used when the file does not provide one")
+	format := fs.String("format", "", "import format: "+strings.Join(importer.Formats, ", ")+", guessed from the file extension if empty")
+	progress := fs.Int("progress", 10000, "report progress every n points")
+	_ = fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		log.Fatal("no file to import")
+	}
+
+	conn, err := grpc.Dial(*uri, grpc.WithInsecure())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer conn.Close()
+
+	c := geottnsvc.NewGeoTTNClient(conn)
+	stream, err := c.StoreStream(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var sent, rejected int
+	for _, path := range fs.Args() {
+		f := *format
+		if f == "" {
+			f = importer.FormatFromPath(path)
+		}
+
+		fd, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		r, err := importer.NewReader(f, fd, *key)
+		if err != nil {
+			log.Fatal(path, ": ", err)
+		}
+
+		for {
+			dp, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if rerr, ok := err.(*importer.RowError); ok {
+				log.Println(path, ":", rerr)
+				rejected++
+				continue
+			}
+			if err != nil {
+				log.Fatal(path, ": ", err)
+			}
+
+			t, _ := ptypes.TimestampProto(dp.Time)
+			err = stream.Send(&geottnsvc.DataPoint{
+				DeviceId:  dp.Key,
+				Latitude:  dp.Lat,
+				Longitude: dp.Lng,
+				Payload:   dp.Value,
+				Time:      t,
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			sent++
+			if *progress > 0 && sent%*progress == 0 {
+				log.Println("sent", sent, "points")
+			}
+		}
+		fd.Close()
+	}
+
+	res, err := stream.CloseAndRecv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// points past the retention are rejected by the server, reported apart from the invalid ones
+	var expired int64
+	for _, e := range res.Errors {
+		if e.Message == storage.ErrExpired.Error() {
+			expired++
+		}
+	}
+	log.Println("stored", res.Stored, "rejected", int64(rejected)+res.Rejected-expired, "expired", expired)
+}
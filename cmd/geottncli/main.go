@@ -1,18 +1,17 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"flag"
 	"log"
 	"os"
 	"time"
 
-	"github.com/akhenakh/cayenne"
 	_ "github.com/mbobakov/grpc-consul-resolver"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/balancer/roundrobin"
 
+	"github.com/akhenakh/geottn/decoder"
 	"github.com/akhenakh/geottn/geottnsvc"
 )
 
@@ -22,9 +21,21 @@ var (
 	lng       = flag.Float64("lng", 2.2, "Lng")
 	radius    = flag.Float64("radius", 1000, "Radius in meters")
 	key       = flag.String("key", "", "ask for a key, if empty perform radius search")
+	nearest   = flag.Int("nearest", 0, "ask for the n nearest devices, rather than a radius search")
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			exportCmd(os.Args[2:])
+			return
+		case "import":
+			importCmd(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Parse()
 
 	conn, err := grpc.Dial(*geoTTNURI,
@@ -48,14 +59,13 @@ func main() {
 			log.Fatal(err)
 		}
 
-		dec := cayenne.NewDecoder(bytes.NewBuffer(dp.Payload))
-		msg, err := dec.DecodeUplink()
+		dec, err := decoder.Decode(dp.Meta.GetDecoder(), dp.Payload)
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		response := make(map[string]interface{})
-		for k, v := range msg.Values() {
+		for k, v := range dec.Values {
 			response[k] = v
 		}
 		response["latitude"] = dp.Latitude
@@ -67,11 +77,20 @@ func main() {
 
 		os.Exit(0)
 	}
-	rep, err := c.RadiusSearch(ctx, &geottnsvc.RadiusSearchRequest{
-		Lat:    *lat,
-		Lng:    *lng,
-		Radius: *radius,
-	})
+	var rep *geottnsvc.DataPoints
+	if *nearest > 0 {
+		rep, err = c.NearestSearch(ctx, &geottnsvc.NearestSearchRequest{
+			Lat:   *lat,
+			Lng:   *lng,
+			Count: int32(*nearest),
+		})
+	} else {
+		rep, err = c.RadiusSearch(ctx, &geottnsvc.RadiusSearchRequest{
+			Lat:    *lat,
+			Lng:    *lng,
+			Radius: *radius,
+		})
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -79,18 +98,18 @@ func main() {
 	log.Println("query ok", len(rep.Points))
 
 	for _, dp := range rep.Points {
-		dec := cayenne.NewDecoder(bytes.NewBuffer(dp.Payload))
-		msg, err := dec.DecodeUplink()
-		if err != nil {
-			log.Fatal(err)
-		}
-
 		response := make(map[string]interface{})
-		for k, v := range msg.Values() {
-			response[k] = v
+
+		// searches are not returning the metadata, payloads not using the default decoder are skipped
+		dec, err := decoder.Decode(dp.Meta.GetDecoder(), dp.Payload)
+		if err == nil {
+			for k, v := range dec.Values {
+				response[k] = v
+			}
 		}
 		response["device_id"] = dp.DeviceId
 		response["time"] = dp.Time
+		response["distance"] = dp.Distance
 
 		log.Println(response)
 	}
@@ -8,10 +8,10 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
-	ttnsdk "github.com/TheThingsNetwork/go-app-sdk"
 	"github.com/dgraph-io/badger/v2"
 	"github.com/dgraph-io/badger/v2/options"
 	log "github.com/go-kit/kit/log"
@@ -30,7 +30,11 @@ import (
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/keepalive"
 
+	"github.com/akhenakh/geottn/decoder"
 	"github.com/akhenakh/geottn/geottnsvc"
+	"github.com/akhenakh/geottn/ingest"
+	_ "github.com/akhenakh/geottn/ingest/all"
+	"github.com/akhenakh/geottn/ingest/ttnv2"
 	badgeridx "github.com/akhenakh/geottn/storage/badger"
 	"github.com/akhenakh/geottn/web"
 )
@@ -40,9 +44,15 @@ const appName = "geottnd"
 var (
 	version = "no version from LDFLAGS"
 
-	appID        = flag.String("appID", "akhtestapp", "The things network application ID")
-	appAccessKey = flag.String("appAccessKey", "", "The things network access key")
-	channel      = flag.Int("channel", 1, "the Cayenne channel where to find gps messages")
+	sources          = flag.String("sources", "", "the ingestion sources to run separated by commas, all the configured sources if empty")
+	channel          = flag.Int("channel", 1, "the Cayenne channel where to find gps messages")
+	defaultDecoder   = flag.String("decoder", decoder.Default, "the default payload decoder: cayenne, fields or json")
+	appDecoders      = flag.String("appDecoders", "", "per application decoders, eg: app1=fields,app2=json")
+	deviceDecoders   = flag.String("deviceDecoders", "", "per device decoders, eg: device1=fields,device2=json")
+	latField         = flag.String("latField", "latitude", "the latitude field path for the fields and json decoders, eg: gps.lat")
+	lngField         = flag.String("lngField", "longitude", "the longitude field path for the fields and json decoders, eg: gps.lng")
+	timeSources      = flag.String("timeSources", "gateway,network,local", "the precedence of the uplink time sources")
+	estimatePosition = flag.Bool("estimatePosition", false, "estimate the position from the gateways for uplinks without gps")
 
 	selfHostedMap = flag.Bool("selfHostedMap", false, "Use a self hosted map rather than MapBox")
 	tilesKey      = flag.String("tilesKey", "", "The key that will passed in the queries to the tiles server")
@@ -52,7 +62,9 @@ var (
 		"the URL where to point to get tiles",
 	)
 
-	dbPath = flag.String("dbPath", "geo.db", "DB path")
+	dbPath          = flag.String("dbPath", "geo.db", "DB path")
+	retention       = flag.Duration("retention", 0, "how long to keep data points, 0 to keep them forever")
+	deviceRetention = flag.String("deviceRetention", "", "per device retention overrides, eg: device1=24h,device2=720h")
 
 	httpMetricsPort = flag.Int("httpMetricsPort", 8888, "http port")
 	httpAPIPort     = flag.Int("httpAPIPort", 9201, "http API port")
@@ -66,6 +78,7 @@ var (
 )
 
 func main() {
+	ingest.RegisterFlags(flag.CommandLine)
 	flag.Parse()
 
 	logger := log.NewJSONLogger(log.NewSyncWriter(os.Stdout))
@@ -98,7 +111,35 @@ func main() {
 		os.Exit(2)
 	}
 
-	idx := &badgeridx.Indexer{DB: bdb}
+	devRetention, err := parseDeviceRetention(*deviceRetention)
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid deviceRetention", "error", err)
+		os.Exit(2)
+	}
+
+	idx := &badgeridx.Indexer{
+		DB:              bdb,
+		Retention:       *retention,
+		DeviceRetention: devRetention,
+	}
+
+	// Badger value log GC, reclaiming space from expired & deleted entries
+	g.Go(func() error {
+		ticker := time.NewTicker(10 * time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+				// run until there is nothing left to rewrite
+				var err error
+				for err == nil {
+					err = bdb.RunValueLogGC(0.5)
+				}
+			}
+		}
+	})
 
 	// gRPC Health Server
 	healthServer := health.NewServer()
@@ -136,7 +177,40 @@ func main() {
 		return nil
 	})
 
-	cfg := geottnsvc.Config{Channel: *channel}
+	tsources, err := geottnsvc.ParseTimeSources(*timeSources)
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid timeSources", "error", err)
+		os.Exit(2)
+	}
+
+	decoder.Register("cayenne", &decoder.Cayenne{Channel: *channel})
+	decoder.Register("fields", &decoder.Fields{Lat: *latField, Lng: *lngField})
+	decoder.Register("json", &decoder.JSON{Lat: *latField, Lng: *lngField})
+
+	if _, err := decoder.Get(*defaultDecoder); err != nil {
+		level.Error(logger).Log("msg", "invalid decoder", "error", err)
+		os.Exit(2)
+	}
+	appDecs, err := decoder.ParseMapping(*appDecoders)
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid appDecoders", "error", err)
+		os.Exit(2)
+	}
+	devDecs, err := decoder.ParseMapping(*deviceDecoders)
+	if err != nil {
+		level.Error(logger).Log("msg", "invalid deviceDecoders", "error", err)
+		os.Exit(2)
+	}
+
+	cfg := geottnsvc.Config{
+		Decoders: decoder.Mapping{
+			Default: *defaultDecoder,
+			Apps:    appDecs,
+			Devices: devDecs,
+		},
+		TimeSources:      tsources,
+		EstimatePosition: *estimatePosition,
+	}
 	s := geottnsvc.NewServer(appName, logger, idx, cfg)
 	s.Health = healthServer
 
@@ -170,6 +244,21 @@ func main() {
 		return grpcServer.Serve(ln)
 	})
 
+	// open the ingestion sources, started once the servers are running
+	var names []string
+	if *sources != "" {
+		names = strings.Split(*sources, ",")
+	}
+	ttnv2.ClientVersion = version
+	srcs, err := ingest.Open(logger, names)
+	if err != nil {
+		level.Error(logger).Log("msg", "can't open sources", "error", err)
+		os.Exit(2)
+	}
+	if len(srcs) == 0 {
+		level.Warn(logger).Log("msg", "no source configured, only receiving data from the gRPC API")
+	}
+
 	// web server
 	g.Go(func() error {
 		// web server
@@ -180,27 +269,43 @@ func main() {
 			SelfHostedMap: *selfHostedMap,
 		}
 
-		s := web.NewServer(appName, logger, idx, cfg)
+		ws := web.NewServer(appName, logger, idx, cfg)
+		ws.Broadcaster = s.Broadcaster
+		ws.DeleteDevice = s.DeleteDevice
 
 		// box html templates
 		box := packr.New("Root box", "./templates")
 
-		s.FileHandler = http.FileServer(box)
-		s.Box = box
+		ws.FileHandler = http.FileServer(box)
+		ws.Box = box
 
 		r := mux.NewRouter()
-		r.HandleFunc("/api/devices", s.DevicesQuery)
-		r.HandleFunc("/api/data/{key}", s.DataQuery)
-		r.HandleFunc("/api/rect/{urlat}/{urlng}/{bllat}/{bllng}", s.RectQuery)
+		r.HandleFunc("/api/devices", ws.DevicesQuery)
+		r.HandleFunc("/api/data/{key}", ws.DeleteQuery).Methods("DELETE")
+		r.HandleFunc("/api/data/{key}", ws.DataQuery)
+		r.HandleFunc("/api/track/{key}", ws.TrackQuery)
+		r.HandleFunc("/api/export/{key}/{format}", ws.ExportQuery)
+		r.HandleFunc("/api/rect/{urlat}/{urlng}/{bllat}/{bllng}", ws.RectQuery)
+		r.HandleFunc("/api/live/{urlat}/{urlng}/{bllat}/{bllng}", ws.LiveQuery)
+		r.HandleFunc("/api/coverage/{urlat}/{urlng}/{bllat}/{bllng}", ws.CoverageQuery)
+		r.HandleFunc("/api/polygon", ws.PolygonQuery).Methods("POST")
+		// sources receiving their uplinks over HTTP, each under its own path
+		for name, src := range srcs {
+			if h, ok := src.(http.Handler); ok {
+				r.PathPrefix("/ingest/" + name + "/").Handler(h)
+			}
+		}
 		r.PathPrefix("/").Handler(
 			handlers.CORS(
-				handlers.AllowedOrigins([]string{"*"}))(s))
+				handlers.AllowedOrigins([]string{"*"}))(ws))
 
+		// the live endpoint clears the WriteTimeout of its connections
 		httpServer = &http.Server{
 			Addr:         fmt.Sprintf(":%d", *httpAPIPort),
 			ReadTimeout:  10 * time.Second,
 			WriteTimeout: 10 * time.Second,
 			Handler:      handlers.CompressHandler(r),
+			ConnContext:  web.ConnContext,
 		}
 		level.Info(logger).Log("msg", fmt.Sprintf("HTTP API server serving at :%d", *httpAPIPort))
 
@@ -211,66 +316,14 @@ func main() {
 		return nil
 	})
 
-	// TTN client subscriptions
-	g.Go(func() error {
-		logger := log.With(logger, "component", "ttnclient")
-		config := ttnsdk.NewCommunityConfig(appName)
-		config.ClientVersion = version
-
-		// Create a new SDK client for the application
-		client := config.NewClient(*appID, *appAccessKey)
-
-		// Make sure the client is closed before the function returns
-		// In your application, you should call this before the application shuts down
-		defer client.Close()
-
-		// Start Publish/Subscribe client (MQTT)
-		pubsub, err := client.PubSub()
-		if err != nil {
-			level.Error(logger).Log("msg", "can't get pub/sub", "error", err)
-			return err
-		}
-
-		// Make sure the pubsub client is closed before the function returns
-		// In your application, you should call this before the application shuts down
-		defer pubsub.Close()
-
-		// Get a publish/subscribe client for all devices
-		allDevicesPubSub := pubsub.AllDevices()
-
-		// Make sure the pubsub client is closed before the function returns
-		// In your application, you will probably call this before the application shuts down
-		// This also stops existing subscriptions, in case you forgot to unsubscribe
-		defer allDevicesPubSub.Close()
-
-		// Subscribe to msgs
-		msgs, err := allDevicesPubSub.SubscribeUplink()
-		if err != nil {
-			level.Error(logger).Log("msg", "can't subscribe to events", "error", err)
-			return err
-		}
-		level.Info(logger).Log("msg", "subscribed to uplink messages")
-
-		for {
-			select {
-			case <-ctx.Done():
-				// Unsubscribe from events
-				level.Info(logger).Log("msg", "unsubscribing to uplink messages")
-
-				if err = allDevicesPubSub.UnsubscribeEvents(); err != nil {
-					level.Error(logger).Log("msg", "can't unsubscribe from events", "error", err)
-					return err
-				}
-				return nil
-			case msg := <-msgs:
-				if msg == nil {
-					break
-				}
-				s.HandleMessage(ctx, msg)
-			}
-
-		}
-	})
+	// run the ingestion sources
+	for name, src := range srcs {
+		name, src := name, src
+		g.Go(func() error {
+			level.Info(logger).Log("msg", "starting source", "source", name)
+			return src.Run(ctx, ingest.Instrument(name, s.HandleUplink))
+		})
+	}
 
 	select {
 	case <-interrupt:
@@ -310,3 +363,24 @@ func main() {
 	}
 
 }
+
+// parseDeviceRetention parses a list of device=duration separated by commas
+func parseDeviceRetention(s string) (map[string]time.Duration, error) {
+	res := make(map[string]time.Duration)
+	if s == "" {
+		return res, nil
+	}
+
+	for _, kv := range strings.Split(s, ",") {
+		skv := strings.SplitN(kv, "=", 2)
+		if len(skv) != 2 {
+			return nil, fmt.Errorf("invalid device retention %q", kv)
+		}
+		d, err := time.ParseDuration(skv[1])
+		if err != nil {
+			return nil, err
+		}
+		res[skv[0]] = d
+	}
+	return res, nil
+}
@@ -0,0 +1,108 @@
+// Package coverage aggregates the signal of the stored uplinks into s2 cells
+package coverage
+
+import (
+	"sort"
+
+	"github.com/golang/geo/s2"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+// Cell is the aggregated signal of the uplinks sent from inside the cell
+type Cell struct {
+	ID    s2.CellID
+	Count int
+
+	// RSSI in dBm, SNR in dB
+	MinRSSI, MaxRSSI, MeanRSSI float32
+	MeanSNR                    float32
+
+	// Gateways is the number of distinct gateways receiving uplinks from this cell
+	Gateways int
+
+	sumRSSI, sumSNR float64
+	gateways        map[string]struct{}
+}
+
+// Grid aggregates the samples at a cell level
+type Grid struct {
+	level   int
+	gateway string
+	cells   map[s2.CellID]*Cell
+}
+
+// NewGrid returns a Grid aggregating at level, for the gateway or combining all gateways if empty
+func NewGrid(level int, gateway string) *Grid {
+	return &Grid{
+		level:   level,
+		gateway: gateway,
+		cells:   make(map[s2.CellID]*Cell),
+	}
+}
+
+// Add aggregates the reception of dp, points without gateway metadata
+// or with a position estimated from the gateways are ignored,
+// combining the gateways, an uplink counts once with its best reception
+func (g *Grid) Add(dp *storage.DataPoint) {
+	if dp.Meta == nil || dp.Meta.Estimated || len(dp.Meta.Gateways) == 0 {
+		return
+	}
+
+	var best *storage.GatewayMetadata
+	for i, gw := range dp.Meta.Gateways {
+		if g.gateway != "" {
+			if gw.ID == g.gateway {
+				best = &dp.Meta.Gateways[i]
+				break
+			}
+			continue
+		}
+		if best == nil || gw.RSSI > best.RSSI {
+			best = &dp.Meta.Gateways[i]
+		}
+	}
+	if best == nil {
+		return
+	}
+
+	id := s2.CellIDFromLatLng(s2.LatLngFromDegrees(dp.Lat, dp.Lng)).Parent(g.level)
+	c, ok := g.cells[id]
+	if !ok {
+		c = &Cell{
+			ID:       id,
+			MinRSSI:  best.RSSI,
+			MaxRSSI:  best.RSSI,
+			gateways: make(map[string]struct{}),
+		}
+		g.cells[id] = c
+	}
+
+	c.Count++
+	c.sumRSSI += float64(best.RSSI)
+	c.sumSNR += float64(best.SNR)
+	c.MeanRSSI = float32(c.sumRSSI / float64(c.Count))
+	c.MeanSNR = float32(c.sumSNR / float64(c.Count))
+	if best.RSSI < c.MinRSSI {
+		c.MinRSSI = best.RSSI
+	}
+	if best.RSSI > c.MaxRSSI {
+		c.MaxRSSI = best.RSSI
+	}
+	for _, gw := range dp.Meta.Gateways {
+		if g.gateway == "" || gw.ID == g.gateway {
+			c.gateways[gw.ID] = struct{}{}
+		}
+	}
+	c.Gateways = len(c.gateways)
+}
+
+// Cells returns the aggregated cells sorted by id
+func (g *Grid) Cells() []Cell {
+	res := make([]Cell, 0, len(g.cells))
+	for _, c := range g.cells {
+		res = append(res, *c)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+	return res
+}
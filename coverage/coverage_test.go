@@ -0,0 +1,60 @@
+package coverage
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+func sample(lat, lng float64, gws ...storage.GatewayMetadata) *storage.DataPoint {
+	return &storage.DataPoint{Lat: lat, Lng: lng, Meta: &storage.Metadata{Gateways: gws}}
+}
+
+func TestGrid(t *testing.T) {
+	dps := []*storage.DataPoint{
+		sample(48.8, 2.2, storage.GatewayMetadata{ID: "gw1", RSSI: -100, SNR: 5}, storage.GatewayMetadata{ID: "gw2", RSSI: -90, SNR: 7}),
+		sample(48.8001, 2.2001, storage.GatewayMetadata{ID: "gw1", RSSI: -110, SNR: -5}),
+		sample(44.8, 2.2, storage.GatewayMetadata{ID: "gw2", RSSI: -120, SNR: -10}),
+		// no radio metadata
+		{Lat: 48.8, Lng: 2.2},
+		// estimated from the gateways
+		{Lat: 48.8, Lng: 2.2, Meta: &storage.Metadata{
+			Gateways:  []storage.GatewayMetadata{{ID: "gw1", RSSI: -60, SNR: 10}},
+			Estimated: true,
+		}},
+	}
+
+	g := NewGrid(12, "")
+	for _, dp := range dps {
+		g.Add(dp)
+	}
+	cells := g.Cells()
+	require.Len(t, cells, 2)
+
+	id := s2.CellIDFromLatLng(s2.LatLngFromDegrees(48.8, 2.2)).Parent(12)
+	var c Cell
+	for _, cc := range cells {
+		if cc.ID == id {
+			c = cc
+		}
+	}
+	require.Equal(t, 2, c.Count)
+	require.Equal(t, 2, c.Gateways)
+	require.Equal(t, float32(-110), c.MinRSSI)
+	require.Equal(t, float32(-90), c.MaxRSSI)
+	require.Equal(t, float32(-100), c.MeanRSSI)
+	require.Equal(t, float32(1), c.MeanSNR)
+
+	g = NewGrid(12, "gw1")
+	for _, dp := range dps {
+		g.Add(dp)
+	}
+	cells = g.Cells()
+	require.Len(t, cells, 1)
+	require.Equal(t, 2, cells[0].Count)
+	require.Equal(t, 1, cells[0].Gateways)
+	require.Equal(t, float32(-105), cells[0].MeanRSSI)
+}
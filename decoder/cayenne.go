@@ -0,0 +1,37 @@
+package decoder
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/akhenakh/cayenne"
+)
+
+// Cayenne decodes Cayenne LPP payloads, the position is read from the GPS channel
+type Cayenne struct {
+	Channel int
+}
+
+// Decode implements Decoder
+func (c *Cayenne) Decode(payload []byte) (*Result, error) {
+	res := &Result{Values: make(map[string]interface{})}
+	if len(payload) == 0 {
+		return res, nil
+	}
+
+	dec := cayenne.NewDecoder(bytes.NewBuffer(payload))
+	msg, err := dec.DecodeUplink()
+	if err != nil {
+		return nil, err
+	}
+	res.Values = msg.Values()
+
+	// latitude, longitude, altitude
+	gps, ok := res.Values[fmt.Sprintf("gps_%d", c.Channel)].([]float32)
+	if ok && len(gps) >= 2 {
+		res.Position = true
+		res.Lat = float64(gps[0])
+		res.Lng = float64(gps[1])
+	}
+	return res, nil
+}
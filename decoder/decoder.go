@@ -0,0 +1,134 @@
+// Package decoder decodes the payloads of the devices into a position and sensor values
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Default is the decoder used when none is configured for a device,
+// and for the data points stored without a decoder name
+const Default = "cayenne"
+
+// ErrUnknownDecoder is returned when no decoder is registered under a name
+var ErrUnknownDecoder = errors.New("unknown decoder")
+
+// Decoder decodes a stored payload
+type Decoder interface {
+	// Decode returns the sensor values of payload and the position if any
+	Decode(payload []byte) (*Result, error)
+}
+
+// FieldsDecoder is implemented by decoders working on the fields decoded by the network server
+// rather than on the raw payload
+type FieldsDecoder interface {
+	Decoder
+
+	// Payload returns the payload to store for the fields
+	Payload(fields map[string]interface{}) ([]byte, error)
+}
+
+// Result is a decoded payload
+type Result struct {
+	Values map[string]interface{}
+
+	// Position is true when Lat & Lng were found in the payload
+	Position bool
+	Lat, Lng float64
+}
+
+var (
+	mu       sync.RWMutex
+	decoders = map[string]Decoder{
+		"cayenne": &Cayenne{Channel: 1},
+		"fields":  &Fields{Lat: "latitude", Lng: "longitude"},
+		"json":    &JSON{Lat: "latitude", Lng: "longitude"},
+	}
+)
+
+// Register registers d under name, replacing any existing decoder
+func Register(name string, d Decoder) {
+	mu.Lock()
+	defer mu.Unlock()
+	decoders[name] = d
+}
+
+// Get returns the decoder registered under name, the Default one if name is empty
+func Get(name string) (Decoder, error) {
+	if name == "" {
+		name = Default
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	d, ok := decoders[name]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownDecoder, name)
+	}
+	return d, nil
+}
+
+// Decode decodes payload with the decoder registered under name, the Default one if name is empty
+func Decode(name string, payload []byte) (*Result, error) {
+	d, err := Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return d.Decode(payload)
+}
+
+// Names returns the sorted names of the registered decoders
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	res := make([]string, 0, len(decoders))
+	for name := range decoders {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Mapping selects the decoder of a device by its device ID, then by its application ID,
+// falling back to Default
+type Mapping struct {
+	Default string
+	Apps    map[string]string
+	Devices map[string]string
+}
+
+// Name returns the decoder name for the device devID of the application appID
+func (m Mapping) Name(appID, devID string) string {
+	if name, ok := m.Devices[devID]; ok {
+		return name
+	}
+	if name, ok := m.Apps[appID]; ok {
+		return name
+	}
+	if m.Default != "" {
+		return m.Default
+	}
+	return Default
+}
+
+// ParseMapping parses a list of id=decoder separated by commas, eg: device1=json,device2=fields
+func ParseMapping(s string) (map[string]string, error) {
+	res := make(map[string]string)
+	if s == "" {
+		return res, nil
+	}
+
+	for _, kv := range strings.Split(s, ",") {
+		skv := strings.SplitN(kv, "=", 2)
+		if len(skv) != 2 {
+			return nil, fmt.Errorf("invalid decoder mapping %q", kv)
+		}
+		if _, err := Get(skv[1]); err != nil {
+			return nil, err
+		}
+		res[skv[0]] = skv[1]
+	}
+	return res, nil
+}
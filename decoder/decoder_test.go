@@ -0,0 +1,79 @@
+package decoder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/akhenakh/cayenne"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCayenne(t *testing.T) {
+	e := cayenne.NewEncoder()
+	e.AddGPS(2, 48.8, 2.2, 10)
+	e.AddTemperature(3, 21.5)
+
+	d := &Cayenne{Channel: 2}
+	res, err := d.Decode(e.Bytes())
+	require.NoError(t, err)
+	require.True(t, res.Position)
+	require.InDelta(t, 48.8, res.Lat, 0.0001)
+	require.InDelta(t, 2.2, res.Lng, 0.0001)
+	require.Contains(t, res.Values, "temperature_3")
+
+	// no gps on that channel
+	d = &Cayenne{Channel: 1}
+	res, err = d.Decode(e.Bytes())
+	require.NoError(t, err)
+	require.False(t, res.Position)
+
+	_, err = d.Decode([]byte{1, 136, 7})
+	require.Error(t, err)
+}
+
+func TestJSON(t *testing.T) {
+	d := &JSON{Lat: "gps.lat", Lng: "gps.lng"}
+	res, err := d.Decode([]byte(`{"gps":{"lat":48.8,"lng":"2.2"},"battery":3.6}`))
+	require.NoError(t, err)
+	require.True(t, res.Position)
+	require.Equal(t, 48.8, res.Lat)
+	require.Equal(t, 2.2, res.Lng)
+	require.Equal(t, 3.6, res.Values["battery"])
+
+	res, err = d.Decode([]byte(`{"gps":{"lat":48.8}}`))
+	require.NoError(t, err)
+	require.False(t, res.Position)
+
+	_, err = d.Decode([]byte(`[1, 2]`))
+	require.Error(t, err)
+
+	f := &Fields{Lat: "Latitude", Lng: "Longitude"}
+	payload, err := f.Payload(map[string]interface{}{"Latitude": 48.8, "Longitude": 2.2, "BatV": 3.1})
+	require.NoError(t, err)
+	res, err = f.Decode(payload)
+	require.NoError(t, err)
+	require.True(t, res.Position)
+	require.Equal(t, 3.1, res.Values["BatV"])
+}
+
+func TestMapping(t *testing.T) {
+	m := Mapping{
+		Apps:    map[string]string{"app1": "fields"},
+		Devices: map[string]string{"dev1": "json"},
+	}
+	require.Equal(t, "json", m.Name("app1", "dev1"))
+	require.Equal(t, "fields", m.Name("app1", "dev2"))
+	require.Equal(t, Default, m.Name("app2", "dev2"))
+
+	devices, err := ParseMapping("dev1=json,dev2=fields")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"dev1": "json", "dev2": "fields"}, devices)
+
+	_, err = ParseMapping("dev1=xml")
+	require.Error(t, err)
+	_, err = ParseMapping("dev1")
+	require.Error(t, err)
+
+	_, err = Get("xml")
+	require.True(t, errors.Is(err, ErrUnknownDecoder))
+}
@@ -0,0 +1,86 @@
+package decoder
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// JSON decodes JSON object payloads, the position is read from the Lat & Lng field paths,
+// nested fields are separated by dots, eg: gps.latitude
+type JSON struct {
+	Lat, Lng string
+}
+
+// Decode implements Decoder
+func (j *JSON) Decode(payload []byte) (*Result, error) {
+	return decodeJSON(payload, j.Lat, j.Lng)
+}
+
+// Fields uses the fields decoded by the network server, eg: the TTN PayloadFields,
+// they are stored as a JSON object, the position is read from the Lat & Lng field paths
+type Fields struct {
+	Lat, Lng string
+}
+
+// Decode implements Decoder
+func (f *Fields) Decode(payload []byte) (*Result, error) {
+	return decodeJSON(payload, f.Lat, f.Lng)
+}
+
+// Payload implements FieldsDecoder
+func (f *Fields) Payload(fields map[string]interface{}) ([]byte, error) {
+	return json.Marshal(fields)
+}
+
+func decodeJSON(payload []byte, latPath, lngPath string) (*Result, error) {
+	res := &Result{Values: make(map[string]interface{})}
+	if len(payload) == 0 {
+		return res, nil
+	}
+
+	if err := json.Unmarshal(payload, &res.Values); err != nil {
+		return nil, err
+	}
+
+	lat, ok := number(lookup(res.Values, latPath))
+	if !ok {
+		return res, nil
+	}
+	lng, ok := number(lookup(res.Values, lngPath))
+	if !ok {
+		return res, nil
+	}
+	res.Position = true
+	res.Lat = lat
+	res.Lng = lng
+	return res, nil
+}
+
+// lookup returns the value at the dot separated path in m
+func lookup(m map[string]interface{}, path string) interface{} {
+	if path == "" {
+		return nil
+	}
+	var v interface{} = m
+	for _, k := range strings.Split(path, ".") {
+		vm, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = vm[k]
+	}
+	return v
+}
+
+// number returns v as a float64, some decoders are returning numbers as strings
+func number(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
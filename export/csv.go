@@ -0,0 +1,87 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+var csvColumns = []string{"device_id", "time", "latitude", "longitude"}
+
+// CSVEncoder writes a row per point, with a column for every sensor value found in the points,
+// the rows are buffered until Close since a device can change its payload layout
+type CSVEncoder struct {
+	w      *csv.Writer
+	rows   []csvRow
+	logger log.Logger
+}
+
+// csvRow is an encoded point waiting for the header
+type csvRow struct {
+	key      string
+	t        time.Time
+	lat, lng float64
+	vals     []value
+}
+
+// NewCSVEncoder returns a CSVEncoder writing to w
+func NewCSVEncoder(w io.Writer, logger log.Logger) *CSVEncoder {
+	return &CSVEncoder{w: csv.NewWriter(w), logger: logger}
+}
+
+// Encode buffers dp as a row
+func (e *CSVEncoder) Encode(dp *storage.DataPoint) error {
+	e.rows = append(e.rows, csvRow{
+		key:  dp.Key,
+		t:    dp.Time,
+		lat:  dp.Lat,
+		lng:  dp.Lng,
+		vals: pointValues(e.logger, dp),
+	})
+	return nil
+}
+
+// Close writes the header and the rows and flushes
+func (e *CSVEncoder) Close() error {
+	index := make(map[string]int)
+	for _, r := range e.rows {
+		for _, v := range r.vals {
+			index[v.Name] = 0
+		}
+	}
+	columns := make([]string, 0, len(index))
+	for name := range index {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	for i, name := range columns {
+		index[name] = len(csvColumns) + i
+	}
+
+	if err := e.w.Write(append(csvColumns, columns...)); err != nil {
+		return err
+	}
+	for _, r := range e.rows {
+		row := make([]string, len(csvColumns)+len(columns))
+		row[0] = r.key
+		row[1] = r.t.UTC().Format(time.RFC3339)
+		row[2] = strconv.FormatFloat(r.lat, 'f', -1, 64)
+		row[3] = strconv.FormatFloat(r.lng, 'f', -1, 64)
+		for _, v := range r.vals {
+			row[index[v.Name]] = v.Value
+		}
+		if err := e.w.Write(row); err != nil {
+			return err
+		}
+	}
+	e.rows = nil
+
+	e.w.Flush()
+	return e.w.Error()
+}
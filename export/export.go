@@ -0,0 +1,117 @@
+// Package export encodes data points history into formats used by GIS and spreadsheet tools
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/geottn/decoder"
+	"github.com/akhenakh/geottn/storage"
+)
+
+// ErrUnknownFormat is returned when asking for an unsupported format
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Formats lists the supported export formats
+var Formats = []string{"gpx", "kml", "csv"}
+
+// Encoder writes data points one by one
+type Encoder interface {
+	Encode(dp *storage.DataPoint) error
+
+	// Close terminates the document, it does not close the underlying writer
+	Close() error
+}
+
+// NewEncoder returns an Encoder writing format to w, name is used as the document title,
+// the payloads that can't be decoded are logged to logger
+func NewEncoder(format string, w io.Writer, name string, logger log.Logger) (Encoder, error) {
+	switch format {
+	case "gpx":
+		return NewGPXEncoder(w, name, logger)
+	case "kml":
+		return NewKMLEncoder(w, name, logger)
+	case "csv":
+		return NewCSVEncoder(w, logger), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// ContentType returns the MIME type for format
+func ContentType(format string) string {
+	switch format {
+	case "gpx":
+		return "application/gpx+xml"
+	case "kml":
+		return "application/vnd.google-earth.kml+xml"
+	case "csv":
+		return "text/csv"
+	}
+	return "application/octet-stream"
+}
+
+// value is a decoded sensor value
+type value struct {
+	Name  string
+	Value string
+}
+
+// values returns the decoded sensor values of dp sorted by name
+func values(dp *storage.DataPoint) ([]value, error) {
+	if len(dp.Value) == 0 {
+		return nil, nil
+	}
+
+	var name string
+	if dp.Meta != nil {
+		name = dp.Meta.Decoder
+	}
+	dec, err := decoder.Decode(name, dp.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	vals := dec.Values
+	res := make([]value, 0, len(vals))
+	for k, v := range vals {
+		s, err := formatValue(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, value{Name: k, Value: s})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res, nil
+}
+
+// formatValue returns the text of a scalar value, the JSON encoding of a nested one
+func formatValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, float32, float64, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// pointValues returns the values of dp, a payload that can't be decoded is logged
+// and the point is exported without values
+func pointValues(logger log.Logger, dp *storage.DataPoint) []value {
+	vals, err := values(dp)
+	if err != nil {
+		level.Warn(logger).Log("msg", "can't decode payload, exporting the position only",
+			"device_id", dp.Key, "time", dp.Time, "error", err)
+		return nil
+	}
+	return vals
+}
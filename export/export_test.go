@@ -0,0 +1,166 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/akhenakh/cayenne"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+func testPoints() []storage.DataPoint {
+	e := cayenne.NewEncoder()
+	e.AddTemperature(2, 21.5)
+	e.AddGPS(1, 48.8, 2.2, 0)
+	t := time.Date(2019, 11, 22, 14, 28, 0, 0, time.UTC)
+	return []storage.DataPoint{
+		{Key: "dev1", Lat: 48.8, Lng: 2.2, Time: t, Value: e.Bytes()},
+		{Key: "dev1", Lat: 48.9, Lng: 2.3, Time: t.Add(time.Minute)},
+	}
+}
+
+func encode(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	enc, err := NewEncoder(format, &buf, "dev1 <track>", log.NewNopLogger())
+	require.NoError(t, err)
+	for _, dp := range testPoints() {
+		dp := dp
+		require.NoError(t, enc.Encode(&dp))
+	}
+	require.NoError(t, enc.Close())
+	return buf.Bytes()
+}
+
+func TestGPX(t *testing.T) {
+	var doc struct {
+		Name   string `xml:"trk>name"`
+		Points []struct {
+			Lat        float64 `xml:"lat,attr"`
+			Lon        float64 `xml:"lon,attr"`
+			Time       string  `xml:"time"`
+			Values     []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"extensions>value"`
+		} `xml:"trk>trkseg>trkpt"`
+	}
+	require.NoError(t, xml.Unmarshal(encode(t, "gpx"), &doc))
+	require.Equal(t, "dev1 <track>", doc.Name)
+	require.Len(t, doc.Points, 2)
+	require.Equal(t, 48.8, doc.Points[0].Lat)
+	require.Equal(t, 2.2, doc.Points[0].Lon)
+	require.Equal(t, "2019-11-22T14:28:00Z", doc.Points[0].Time)
+	require.Len(t, doc.Points[0].Values, 2)
+	require.Equal(t, "temperature_2", doc.Points[0].Values[1].Name)
+	require.Equal(t, "21.5", doc.Points[0].Values[1].Value)
+	require.Len(t, doc.Points[1].Values, 0)
+}
+
+func TestValueNames(t *testing.T) {
+	dps := []storage.DataPoint{
+		{
+			Key: "dev1", Lat: 48.8, Lng: 2.2, Time: time.Date(2019, 11, 22, 14, 28, 0, 0, time.UTC),
+			Value: []byte(`{"battery level": 3.6, "1st": "<a&b>", "gps": {"lat": 48.8}}`),
+			Meta:  &storage.Metadata{Decoder: "json"},
+		},
+		{
+			Key: "dev1", Lat: 48.9, Lng: 2.3, Time: time.Date(2019, 11, 22, 14, 29, 0, 0, time.UTC),
+			Value: []byte(`{"temperature": 21.5}`),
+			Meta:  &storage.Metadata{Decoder: "json"},
+		},
+	}
+	encode := func(format string) []byte {
+		var buf bytes.Buffer
+		enc, err := NewEncoder(format, &buf, "dev1", log.NewNopLogger())
+		require.NoError(t, err)
+		for _, dp := range dps {
+			dp := dp
+			require.NoError(t, enc.Encode(&dp))
+		}
+		require.NoError(t, enc.Close())
+		return buf.Bytes()
+	}
+
+	var doc struct {
+		Points []struct {
+			Values []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:",chardata"`
+			} `xml:"extensions>value"`
+		} `xml:"trk>trkseg>trkpt"`
+	}
+	require.NoError(t, xml.Unmarshal(encode("gpx"), &doc))
+	require.Len(t, doc.Points, 2)
+	require.Len(t, doc.Points[0].Values, 3)
+	require.Equal(t, "1st", doc.Points[0].Values[0].Name)
+	require.Equal(t, "<a&b>", doc.Points[0].Values[0].Value)
+	require.Equal(t, "battery level", doc.Points[0].Values[1].Name)
+	require.Equal(t, "3.6", doc.Points[0].Values[1].Value)
+	require.Equal(t, `{"lat":48.8}`, doc.Points[0].Values[2].Value)
+
+	// the columns of all the points
+	rows, err := csv.NewReader(bytes.NewReader(encode("csv"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, []string{"device_id", "time", "latitude", "longitude", "1st", "battery level", "gps", "temperature"}, rows[0])
+	require.Equal(t, []string{"dev1", "2019-11-22T14:28:00Z", "48.8", "2.2", "<a&b>", "3.6", `{"lat":48.8}`, ""}, rows[1])
+	require.Equal(t, "21.5", rows[2][7])
+}
+
+func TestKML(t *testing.T) {
+	var doc struct {
+		Placemarks []struct {
+			When        string `xml:"TimeStamp>when"`
+			Coordinates string `xml:"Point>coordinates"`
+			Data        []struct {
+				Name  string `xml:"name,attr"`
+				Value string `xml:"value"`
+			} `xml:"ExtendedData>Data"`
+		} `xml:"Document>Placemark"`
+	}
+	require.NoError(t, xml.Unmarshal(encode(t, "kml"), &doc))
+	require.Len(t, doc.Placemarks, 2)
+	require.Equal(t, "2.2,48.8", doc.Placemarks[0].Coordinates)
+	require.Equal(t, "2019-11-22T14:29:00Z", doc.Placemarks[1].When)
+	require.Len(t, doc.Placemarks[0].Data, 2)
+	require.Equal(t, "temperature_2", doc.Placemarks[0].Data[1].Name)
+	require.Equal(t, "21.5", doc.Placemarks[0].Data[1].Value)
+}
+
+func TestCSV(t *testing.T) {
+	rows, err := csv.NewReader(bytes.NewReader(encode(t, "csv"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	require.Equal(t, []string{"device_id", "time", "latitude", "longitude", "gps_1", "temperature_2"}, rows[0])
+	require.Equal(t, "21.5", rows[1][5])
+	require.Equal(t, "", rows[2][5])
+	require.Equal(t, "48.9", rows[2][2])
+}
+
+func TestUndecodablePayload(t *testing.T) {
+	dps := testPoints()
+	dps[0].Value = []byte{0x01}
+	for _, format := range Formats {
+		var buf bytes.Buffer
+		enc, err := NewEncoder(format, &buf, "dev1", log.NewNopLogger())
+		require.NoError(t, err)
+		for _, dp := range dps {
+			dp := dp
+			require.NoError(t, enc.Encode(&dp), format)
+		}
+		require.NoError(t, enc.Close())
+		require.Contains(t, buf.String(), "2019-11-22T14:28:00Z", format)
+		require.Contains(t, buf.String(), "2019-11-22T14:29:00Z", format)
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewEncoder("shp", &bytes.Buffer{}, "", log.NewNopLogger())
+	require.Equal(t, ErrUnknownFormat, err)
+}
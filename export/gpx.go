@@ -0,0 +1,80 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+const gpxHeader = `<gpx version="1.1" creator="geottn" xmlns="http://www.topografix.com/GPX/1/1" xmlns:geottn="https://github.com/akhenakh/geottn">`
+
+// GPXEncoder writes a GPX 1.1 track, sensor values are stored in the trkpt extensions
+// as <geottn:value name="temperature_2">21.5</geottn:value> elements
+type GPXEncoder struct {
+	w      io.Writer
+	enc    *xml.Encoder
+	logger log.Logger
+}
+
+type gpxPoint struct {
+	XMLName    xml.Name      `xml:"trkpt"`
+	Lat        float64       `xml:"lat,attr"`
+	Lon        float64       `xml:"lon,attr"`
+	Time       string        `xml:"time"`
+	Extensions *gpxExtension `xml:"extensions,omitempty"`
+}
+
+type gpxExtension struct {
+	Values []gpxValue
+}
+
+type gpxValue struct {
+	XMLName xml.Name `xml:"geottn:value"`
+	Name    string   `xml:"name,attr"`
+	Value   string   `xml:",chardata"`
+}
+
+// NewGPXEncoder writes the GPX header to w with a track called name
+func NewGPXEncoder(w io.Writer, name string, logger log.Logger) (*GPXEncoder, error) {
+	if _, err := io.WriteString(w, xml.Header+gpxHeader+"<trk><name>"); err != nil {
+		return nil, err
+	}
+	if err := xml.EscapeText(w, []byte(name)); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, "</name><trkseg>"); err != nil {
+		return nil, err
+	}
+	return &GPXEncoder{w: w, enc: xml.NewEncoder(w), logger: logger}, nil
+}
+
+// Encode writes dp as a track point
+func (e *GPXEncoder) Encode(dp *storage.DataPoint) error {
+	vals := pointValues(e.logger, dp)
+
+	p := gpxPoint{
+		Lat:  dp.Lat,
+		Lon:  dp.Lng,
+		Time: dp.Time.UTC().Format(time.RFC3339),
+	}
+	if len(vals) > 0 {
+		p.Extensions = &gpxExtension{Values: make([]gpxValue, len(vals))}
+		for i, v := range vals {
+			p.Extensions.Values[i] = gpxValue{Name: v.Name, Value: v.Value}
+		}
+	}
+	return e.enc.Encode(p)
+}
+
+// Close writes the GPX footer
+func (e *GPXEncoder) Close() error {
+	if err := e.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.w, "</trkseg></trk></gpx>\n")
+	return err
+}
@@ -0,0 +1,82 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+const kmlHeader = `<kml xmlns="http://www.opengis.net/kml/2.2">`
+
+// KMLEncoder writes a KML document with a timestamped placemark per point,
+// sensor values are stored as ExtendedData
+type KMLEncoder struct {
+	w      io.Writer
+	enc    *xml.Encoder
+	logger log.Logger
+}
+
+type kmlPlacemark struct {
+	XMLName      xml.Name     `xml:"Placemark"`
+	Name         string       `xml:"name"`
+	When         string       `xml:"TimeStamp>when"`
+	ExtendedData *kmlExtended `xml:"ExtendedData,omitempty"`
+	Coordinates  string       `xml:"Point>coordinates"`
+}
+
+type kmlExtended struct {
+	Data []kmlData `xml:"Data"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+// NewKMLEncoder writes the KML header to w with a document called name
+func NewKMLEncoder(w io.Writer, name string, logger log.Logger) (*KMLEncoder, error) {
+	if _, err := io.WriteString(w, xml.Header+kmlHeader+"<Document><name>"); err != nil {
+		return nil, err
+	}
+	if err := xml.EscapeText(w, []byte(name)); err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(w, "</name>"); err != nil {
+		return nil, err
+	}
+	return &KMLEncoder{w: w, enc: xml.NewEncoder(w), logger: logger}, nil
+}
+
+// Encode writes dp as a placemark
+func (e *KMLEncoder) Encode(dp *storage.DataPoint) error {
+	vals := pointValues(e.logger, dp)
+
+	t := dp.Time.UTC().Format(time.RFC3339)
+	p := kmlPlacemark{
+		Name: t,
+		When: t,
+		Coordinates: strconv.FormatFloat(dp.Lng, 'f', -1, 64) + "," +
+			strconv.FormatFloat(dp.Lat, 'f', -1, 64),
+	}
+	if len(vals) > 0 {
+		p.ExtendedData = &kmlExtended{Data: make([]kmlData, len(vals))}
+		for i, v := range vals {
+			p.ExtendedData.Data[i] = kmlData{Name: v.Name, Value: v.Value}
+		}
+	}
+	return e.enc.Encode(p)
+}
+
+// Close writes the KML footer
+func (e *KMLEncoder) Close() error {
+	if err := e.enc.Flush(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(e.w, "</Document></kml>\n")
+	return err
+}
@@ -0,0 +1,145 @@
+// Package geoloc estimates the position of a device from the gateways receiving its uplinks
+package geoloc
+
+import (
+	"math"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+const (
+	// rssiAt1m is the expected RSSI in dBm at one meter of a 14 dBm device
+	rssiAt1m = -20.0
+
+	// pathLossExponent models the signal attenuation, 2 in free space, higher in urban areas
+	pathLossExponent = 2.7
+
+	// minAccuracy is the best accuracy in meters an RSSI based estimate can claim
+	minAccuracy = 100.0
+
+	// multilaterationIterations is the number of Gauss-Newton iterations
+	multilaterationIterations = 20
+
+	earthRadiusMeter = 6371010.0
+)
+
+// Estimate returns the position of a device and an accuracy radius in meters
+// from the gateways with a known location and a valid RSSI that received its uplink,
+// using RSSI multilateration with three gateways or more, a weighted centroid otherwise
+// or when the multilateration is farther from the gateways than the estimated distances,
+// ok is false when no gateway location is known
+func Estimate(gws []storage.GatewayMetadata) (lat, lng, accuracy float64, ok bool) {
+	var located []storage.GatewayMetadata
+	for _, gw := range gws {
+		if gw.Lat == 0 && gw.Lng == 0 {
+			continue
+		}
+		// a missing or bogus RSSI
+		if gw.RSSI >= 0 {
+			continue
+		}
+		located = append(located, gw)
+	}
+	if len(located) == 0 {
+		return 0, 0, 0, false
+	}
+
+	// positions in meters on a plane tangent at the first gateway
+	lat0, lng0 := located[0].Lat, located[0].Lng
+	cosLat0 := math.Cos(lat0 * math.Pi / 180)
+	xs := make([]float64, len(located))
+	ys := make([]float64, len(located))
+	ds := make([]float64, len(located))
+	for i, gw := range located {
+		xs[i] = (gw.Lng - lng0) * math.Pi / 180 * earthRadiusMeter * cosLat0
+		ys[i] = (gw.Lat - lat0) * math.Pi / 180 * earthRadiusMeter
+		ds[i] = Distance(gw.RSSI)
+	}
+
+	x, y, acc := centroid(xs, ys, ds)
+	if len(located) >= 3 {
+		if mx, my, macc, mok := multilaterate(xs, ys, ds, x, y); mok && plausible(xs, ys, ds, mx, my) {
+			x, y, acc = mx, my, macc
+		}
+	}
+
+	lat = lat0 + y/earthRadiusMeter*180/math.Pi
+	lng = lng0 + x/(earthRadiusMeter*cosLat0)*180/math.Pi
+	return lat, lng, math.Max(acc, minAccuracy), true
+}
+
+// Distance returns the distance in meters estimated from rssi using a log-distance path loss model
+func Distance(rssi float32) float64 {
+	return math.Pow(10, (rssiAt1m-float64(rssi))/(10*pathLossExponent))
+}
+
+// centroid returns the centroid of the gateways weighted by the inverse of their distance,
+// the accuracy is the weighted mean distance
+func centroid(xs, ys, ds []float64) (x, y, accuracy float64) {
+	var sw float64
+	for i := range xs {
+		w := 1 / ds[i]
+		x += w * xs[i]
+		y += w * ys[i]
+		accuracy += w * ds[i]
+		sw += w
+	}
+	return x / sw, y / sw, accuracy / sw
+}
+
+// multilaterate finds the point whose distances to the gateways best match ds, starting from x, y,
+// the accuracy is the root mean square of the distances errors, ok is false if it does not converge
+func multilaterate(xs, ys, ds []float64, x, y float64) (float64, float64, float64, bool) {
+	for it := 0; it < multilaterationIterations; it++ {
+		// normal equations of the linearized problem
+		var a11, a12, a22, b1, b2 float64
+		for i := range xs {
+			dx, dy := x-xs[i], y-ys[i]
+			d := math.Hypot(dx, dy)
+			if d < 1e-6 {
+				d = 1e-6
+			}
+			jx, jy := dx/d, dy/d
+			r := d - ds[i]
+			a11 += jx * jx
+			a12 += jx * jy
+			a22 += jy * jy
+			b1 -= jx * r
+			b2 -= jy * r
+		}
+
+		det := a11*a22 - a12*a12
+		if math.Abs(det) < 1e-9 {
+			// aligned gateways
+			return 0, 0, 0, false
+		}
+		sx := (a22*b1 - a12*b2) / det
+		sy := (a11*b2 - a12*b1) / det
+		x += sx
+		y += sy
+		if math.Hypot(sx, sy) < 0.1 {
+			break
+		}
+	}
+
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return 0, 0, 0, false
+	}
+
+	var sum float64
+	for i := range xs {
+		r := math.Hypot(x-xs[i], y-ys[i]) - ds[i]
+		sum += r * r
+	}
+	return x, y, math.Sqrt(sum / float64(len(xs))), true
+}
+
+// plausible reports whether the nearest gateway to x, y is within the largest estimated distance
+func plausible(xs, ys, ds []float64, x, y float64) bool {
+	nearest, farthest := math.Inf(1), 0.0
+	for i := range xs {
+		nearest = math.Min(nearest, math.Hypot(x-xs[i], y-ys[i]))
+		farthest = math.Max(farthest, ds[i])
+	}
+	return nearest <= farthest
+}
@@ -0,0 +1,81 @@
+package geoloc
+
+import (
+	"math"
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+// rssi returns the RSSI expected at distance meters, the inverse of Distance
+func rssi(distance float64) float32 {
+	return float32(rssiAt1m - 10*pathLossExponent*math.Log10(distance))
+}
+
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+	a := s2.LatLngFromDegrees(lat1, lng1)
+	b := s2.LatLngFromDegrees(lat2, lng2)
+	return a.Distance(b).Radians() * earthRadiusMeter
+}
+
+func TestEstimate(t *testing.T) {
+	_, _, _, ok := Estimate([]storage.GatewayMetadata{{ID: "nolocation", RSSI: -100}})
+	require.False(t, ok)
+
+	// a single gateway, the device is around it
+	lat, lng, acc, ok := Estimate([]storage.GatewayMetadata{{ID: "gw1", RSSI: rssi(2000), Lat: 48.8, Lng: 2.2}})
+	require.True(t, ok)
+	require.Equal(t, 48.8, lat)
+	require.Equal(t, 2.2, lng)
+	require.InDelta(t, 2000, acc, 1)
+
+	// the device is closer to gw1
+	lat, _, _, ok = Estimate([]storage.GatewayMetadata{
+		{ID: "gw1", RSSI: rssi(1000), Lat: 48.8, Lng: 2.2},
+		{ID: "gw2", RSSI: rssi(3000), Lat: 48.84, Lng: 2.2},
+	})
+	require.True(t, ok)
+	require.InDelta(t, 48.81, lat, 0.0001)
+
+	// multilateration with exact distances
+	devLat, devLng := 48.81, 2.21
+	var gws []storage.GatewayMetadata
+	for _, p := range [][2]float64{{48.8, 2.2}, {48.84, 2.19}, {48.79, 2.26}, {48.83, 2.25}} {
+		gws = append(gws, storage.GatewayMetadata{
+			RSSI: rssi(distance(devLat, devLng, p[0], p[1])),
+			Lat:  p[0],
+			Lng:  p[1],
+		})
+	}
+	lat, lng, acc, ok = Estimate(gws)
+	require.True(t, ok)
+	require.Less(t, distance(devLat, devLng, lat, lng), 20.0)
+	require.Equal(t, minAccuracy, acc)
+}
+
+func TestEstimateInvalid(t *testing.T) {
+	// a gateway without RSSI is ignored
+	_, _, _, ok := Estimate([]storage.GatewayMetadata{{ID: "gw1", RSSI: 0, Lat: 48.8, Lng: 2.2}})
+	require.False(t, ok)
+
+	lat, _, _, ok := Estimate([]storage.GatewayMetadata{
+		{ID: "gw1", RSSI: rssi(1000), Lat: 48.8, Lng: 2.2},
+		{ID: "gw2", RSSI: 0, Lat: 48.84, Lng: 2.2},
+	})
+	require.True(t, ok)
+	require.Equal(t, 48.8, lat)
+
+	// the gateways can't all be 100m away, the multilateration is discarded for the centroid
+	lat, lng, acc, ok := Estimate([]storage.GatewayMetadata{
+		{ID: "gw1", RSSI: rssi(100), Lat: 48.8, Lng: 2.2},
+		{ID: "gw2", RSSI: rssi(100), Lat: 48.84, Lng: 2.2},
+		{ID: "gw3", RSSI: rssi(100), Lat: 48.82, Lng: 2.25},
+	})
+	require.True(t, ok)
+	require.InDelta(t, 48.82, lat, 0.0001)
+	require.InDelta(t, (2.2+2.2+2.25)/3, lng, 0.0001)
+	require.Equal(t, minAccuracy, acc)
+}
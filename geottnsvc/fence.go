@@ -0,0 +1,185 @@
+package geottnsvc
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/empty"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+func (s *Server) PutGeofence(ctx context.Context, f *Geofence) (*empty.Empty, error) {
+	e := &empty.Empty{}
+	sf, err := GeofenceToStorage(f)
+	if err != nil {
+		return e, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = s.GeoDB.StoreFence(sf)
+	s.resetFences()
+	if err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+func (s *Server) GetGeofence(ctx context.Context, req *GeofenceRequest) (*Geofence, error) {
+	f, err := s.GeoDB.GetFence(req.Id)
+	if err == storage.ErrNotFound {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return StorageToGeofence(f), nil
+}
+
+func (s *Server) DeleteGeofence(ctx context.Context, req *GeofenceRequest) (*empty.Empty, error) {
+	e := &empty.Empty{}
+	err := s.GeoDB.DeleteFence(req.Id)
+	s.resetFences()
+	if err != nil {
+		return e, err
+	}
+	return e, nil
+}
+
+// fenceRegion is a fence with its parsed region
+type fenceRegion struct {
+	ID     string
+	Region s2.Region
+}
+
+// fenceRegions returns the fences regions, parsed once until the fences change
+func (s *Server) fenceRegions() ([]fenceRegion, error) {
+	s.fencesMu.Lock()
+	defer s.fencesMu.Unlock()
+	if s.fences != nil {
+		return s.fences, nil
+	}
+
+	fences, err := s.GeoDB.Fences()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]fenceRegion, 0, len(fences))
+	for _, f := range fences {
+		r, err := f.Region()
+		if err != nil {
+			level.Error(s.logger).Log("msg", "invalid fence", "fence_id", f.ID, "error", err)
+			continue
+		}
+		res = append(res, fenceRegion{ID: f.ID, Region: r})
+	}
+	s.fences = res
+	return res, nil
+}
+
+// resetFences invalidates the parsed fences after a change
+func (s *Server) resetFences() {
+	s.fencesMu.Lock()
+	s.fences = nil
+	s.fencesMu.Unlock()
+}
+
+func (s *Server) ListGeofences(context.Context, *empty.Empty) (*Geofences, error) {
+	fences, err := s.GeoDB.Fences()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Geofences{
+		Geofences: make([]*Geofence, len(fences)),
+	}
+	for i, f := range fences {
+		res.Geofences[i] = StorageToGeofence(&f)
+	}
+	return res, nil
+}
+
+func (s *Server) GeofenceEvents(ctx context.Context, req *GeofenceEventsRequest) (*GeofenceEventList, error) {
+	start, end, err := timeRange(req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []storage.GeofenceEvent
+	var next []byte
+	switch {
+	case req.FenceId != "":
+		events, next, err = s.GeoDB.FenceEvents(req.FenceId, start, end, req.Cursor, int(req.Count))
+	case req.DeviceId != "":
+		events, next, err = s.GeoDB.DeviceFenceEvents(req.DeviceId, start, end, req.Cursor, int(req.Count))
+	default:
+		return nil, status.Error(codes.InvalidArgument, "fence_id or device_id required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := &GeofenceEventList{
+		Events:     make([]*GeofenceEvent, len(events)),
+		NextCursor: next,
+	}
+	for i, e := range events {
+		t, _ := ptypes.TimestampProto(e.Time)
+		res.Events[i] = &GeofenceEvent{
+			FenceId:   e.FenceID,
+			DeviceId:  e.Key,
+			Type:      GeofenceEvent_Type(e.Type),
+			Time:      t,
+			Latitude:  e.Lat,
+			Longitude: e.Lng,
+		}
+	}
+	return res, nil
+}
+
+// GeofenceToStorage converts a Geofence into a storage Geofence
+func GeofenceToStorage(f *Geofence) (*storage.Geofence, error) {
+	sf := &storage.Geofence{
+		ID:     f.Id,
+		Name:   f.Name,
+		Lat:    f.Lat,
+		Lng:    f.Lng,
+		Radius: f.Radius,
+	}
+	if f.Polygon != nil {
+		sf.Rings = polygonRings(f.Polygon)
+	}
+	if _, err := sf.Region(); err != nil {
+		return nil, err
+	}
+	return sf, nil
+}
+
+// StorageToGeofence converts a storage Geofence into a Geofence
+func StorageToGeofence(f *storage.Geofence) *Geofence {
+	if f == nil {
+		return nil
+	}
+	res := &Geofence{
+		Id:     f.ID,
+		Name:   f.Name,
+		Lat:    f.Lat,
+		Lng:    f.Lng,
+		Radius: f.Radius,
+	}
+	if len(f.Rings) > 0 {
+		res.Polygon = &Polygon{Rings: make([]*Ring, len(f.Rings))}
+		for i, r := range f.Rings {
+			res.Polygon.Rings[i] = &Ring{Points: make([]*LatLng, len(r))}
+			for j, ll := range r {
+				res.Polygon.Rings[i].Points[j] = &LatLng{Lat: ll.Lat.Degrees(), Lng: ll.Lng.Degrees()}
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,109 @@
+package geottnsvc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+func TestStorePointFenceEvents(t *testing.T) {
+	s, idx, clean := newTestServer(t)
+	defer clean()
+
+	err := idx.StoreFence(&storage.Geofence{ID: "depot", Lat: 48.8, Lng: 2.2, Radius: 1000})
+	require.NoError(t, err)
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	// outside, entering, staying, older point inside, exiting
+	require.NoError(t, s.storePoint("KEY", nil, 48.9, 2.2, ts))
+	require.NoError(t, s.storePoint("KEY", nil, 48.8, 2.2, ts.Add(time.Minute)))
+	require.NoError(t, s.storePoint("KEY", nil, 48.801, 2.2, ts.Add(2*time.Minute)))
+	require.NoError(t, s.storePoint("KEY", nil, 48.801, 2.2, ts.Add(-time.Minute)))
+	require.NoError(t, s.storePoint("KEY", nil, 48.9, 2.2, ts.Add(3*time.Minute)))
+
+	events, _, err := idx.DeviceFenceEvents("KEY", storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, storage.FenceExit, events[0].Type)
+	require.Equal(t, ts.Add(3*time.Minute), events[0].Time)
+	require.Equal(t, storage.FenceEnter, events[1].Type)
+	require.Equal(t, ts.Add(time.Minute), events[1].Time)
+
+	// a first point inside is entering
+	require.NoError(t, s.storePoint("KEY2", nil, 48.8, 2.2, ts))
+	events, _, err = idx.FenceEvents("depot", storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, "KEY2", events[2].Key)
+}
+
+func TestStorePointFenceEventsConcurrent(t *testing.T) {
+	s, idx, clean := newTestServer(t)
+	defer clean()
+
+	err := idx.StoreFence(&storage.Geofence{ID: "depot", Lat: 48.8, Lng: 2.2, Radius: 1000})
+	require.NoError(t, err)
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				lat := 48.8
+				if (g+i)%2 == 0 {
+					lat = 48.9
+				}
+				if err := s.storePoint("KEY", nil, lat, 2.2, ts.Add(time.Duration(i*8+g)*time.Second)); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// whatever the order the points were stored in, the events must alternate
+	events, _, err := idx.DeviceFenceEvents("KEY", storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	for i := 1; i < len(events); i++ {
+		require.NotEqual(t, events[i-1].Type, events[i].Type)
+	}
+}
+
+func TestStorePointFenceChanges(t *testing.T) {
+	s, idx, clean := newTestServer(t)
+	defer clean()
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, s.storePoint("KEY", nil, 48.9, 2.2, ts))
+
+	// the fences parsed for the previous point are replaced
+	_, err := s.PutGeofence(context.Background(), &Geofence{Id: "depot", Lat: 48.8, Lng: 2.2, Radius: 1000})
+	require.NoError(t, err)
+	require.NoError(t, s.storePoint("KEY", nil, 48.8, 2.2, ts.Add(time.Minute)))
+
+	_, err = s.DeleteGeofence(context.Background(), &GeofenceRequest{Id: "depot"})
+	require.NoError(t, err)
+	require.NoError(t, s.storePoint("KEY", nil, 48.9, 2.2, ts.Add(2*time.Minute)))
+
+	events, _, err := idx.DeviceFenceEvents("KEY", storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 0)
+
+	_, err = s.PutGeofence(context.Background(), &Geofence{Id: "depot", Lat: 48.8, Lng: 2.2, Radius: 1000})
+	require.NoError(t, err)
+	require.NoError(t, s.storePoint("KEY", nil, 48.8, 2.2, ts.Add(3*time.Minute)))
+
+	events, _, err = idx.DeviceFenceEvents("KEY", storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, storage.FenceEnter, events[0].Type)
+}
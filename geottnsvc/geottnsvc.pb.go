@@ -3,15 +3,16 @@
 
 package geottnsvc
 
-import proto "github.com/golang/protobuf/proto"
-import fmt "fmt"
-import math "math"
-import empty "github.com/golang/protobuf/ptypes/empty"
-import timestamp "github.com/golang/protobuf/ptypes/timestamp"
-
 import (
-	context "golang.org/x/net/context"
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
 	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
 )
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -23,34 +24,67 @@ var _ = math.Inf
 // is compatible with the proto package it is being compiled against.
 // A compilation error at this line likely means your copy of the
 // proto package needs to be updated.
-const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type GeofenceEvent_Type int32
+
+const (
+	GeofenceEvent_UNKNOWN GeofenceEvent_Type = 0
+	GeofenceEvent_ENTER   GeofenceEvent_Type = 1
+	GeofenceEvent_EXIT    GeofenceEvent_Type = 2
+)
+
+var GeofenceEvent_Type_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "ENTER",
+	2: "EXIT",
+}
+
+var GeofenceEvent_Type_value = map[string]int32{
+	"UNKNOWN": 0,
+	"ENTER":   1,
+	"EXIT":    2,
+}
+
+func (x GeofenceEvent_Type) String() string {
+	return proto.EnumName(GeofenceEvent_Type_name, int32(x))
+}
+
+func (GeofenceEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{24, 0}
+}
 
 type DataPoint struct {
-	AppId                string               `protobuf:"bytes,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
-	DeviceId             string               `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
-	Latitude             float64              `protobuf:"fixed64,3,opt,name=latitude,proto3" json:"latitude,omitempty"`
-	Longitude            float64              `protobuf:"fixed64,4,opt,name=longitude,proto3" json:"longitude,omitempty"`
-	Time                 *timestamp.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
-	Payload              []byte               `protobuf:"bytes,6,opt,name=payload,proto3" json:"payload,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
-	XXX_unrecognized     []byte               `json:"-"`
-	XXX_sizecache        int32                `json:"-"`
+	AppId     string               `protobuf:"bytes,1,opt,name=app_id,json=appId,proto3" json:"app_id,omitempty"`
+	DeviceId  string               `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Latitude  float64              `protobuf:"fixed64,3,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude float64              `protobuf:"fixed64,4,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Time      *timestamp.Timestamp `protobuf:"bytes,5,opt,name=time,proto3" json:"time,omitempty"`
+	Payload   []byte               `protobuf:"bytes,6,opt,name=payload,proto3" json:"payload,omitempty"`
+	// distance in meters from the queried point, for searches around a point
+	Distance float64 `protobuf:"fixed64,7,opt,name=distance,proto3" json:"distance,omitempty"`
+	// only returned when querying a device
+	Meta                 *Metadata `protobuf:"bytes,8,opt,name=meta,proto3" json:"meta,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
 }
 
 func (m *DataPoint) Reset()         { *m = DataPoint{} }
 func (m *DataPoint) String() string { return proto.CompactTextString(m) }
 func (*DataPoint) ProtoMessage()    {}
 func (*DataPoint) Descriptor() ([]byte, []int) {
-	return fileDescriptor_geottnsvc_d04c6f621b8ce5f8, []int{0}
+	return fileDescriptor_06af7e6094328dd5, []int{0}
 }
+
 func (m *DataPoint) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DataPoint.Unmarshal(m, b)
 }
 func (m *DataPoint) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	return xxx_messageInfo_DataPoint.Marshal(b, m, deterministic)
 }
-func (dst *DataPoint) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_DataPoint.Merge(dst, src)
+func (m *DataPoint) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DataPoint.Merge(m, src)
 }
 func (m *DataPoint) XXX_Size() int {
 	return xxx_messageInfo_DataPoint.Size(m)
@@ -103,6 +137,204 @@ func (m *DataPoint) GetPayload() []byte {
 	return nil
 }
 
+func (m *DataPoint) GetDistance() float64 {
+	if m != nil {
+		return m.Distance
+	}
+	return 0
+}
+
+func (m *DataPoint) GetMeta() *Metadata {
+	if m != nil {
+		return m.Meta
+	}
+	return nil
+}
+
+// Metadata is stored alongside a data point
+type Metadata struct {
+	// where the time comes from: gateway, network or local
+	TimeSource string `protobuf:"bytes,1,opt,name=time_source,json=timeSource,proto3" json:"time_source,omitempty"`
+	Fcnt       uint32 `protobuf:"varint,2,opt,name=fcnt,proto3" json:"fcnt,omitempty"`
+	// eg SF7BW125
+	DataRate string `protobuf:"bytes,3,opt,name=data_rate,json=dataRate,proto3" json:"data_rate,omitempty"`
+	// in MHz
+	Frequency float32            `protobuf:"fixed32,4,opt,name=frequency,proto3" json:"frequency,omitempty"`
+	Gateways  []*GatewayMetadata `protobuf:"bytes,5,rep,name=gateways,proto3" json:"gateways,omitempty"`
+	// the position was estimated from the gateways locations, within accuracy meters
+	Estimated bool    `protobuf:"varint,6,opt,name=estimated,proto3" json:"estimated,omitempty"`
+	Accuracy  float64 `protobuf:"fixed64,7,opt,name=accuracy,proto3" json:"accuracy,omitempty"`
+	// the payload decoder name, empty for the default one
+	Decoder              string   `protobuf:"bytes,8,opt,name=decoder,proto3" json:"decoder,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Metadata) Reset()         { *m = Metadata{} }
+func (m *Metadata) String() string { return proto.CompactTextString(m) }
+func (*Metadata) ProtoMessage()    {}
+func (*Metadata) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{1}
+}
+
+func (m *Metadata) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Metadata.Unmarshal(m, b)
+}
+func (m *Metadata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Metadata.Marshal(b, m, deterministic)
+}
+func (m *Metadata) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Metadata.Merge(m, src)
+}
+func (m *Metadata) XXX_Size() int {
+	return xxx_messageInfo_Metadata.Size(m)
+}
+func (m *Metadata) XXX_DiscardUnknown() {
+	xxx_messageInfo_Metadata.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Metadata proto.InternalMessageInfo
+
+func (m *Metadata) GetTimeSource() string {
+	if m != nil {
+		return m.TimeSource
+	}
+	return ""
+}
+
+func (m *Metadata) GetFcnt() uint32 {
+	if m != nil {
+		return m.Fcnt
+	}
+	return 0
+}
+
+func (m *Metadata) GetDataRate() string {
+	if m != nil {
+		return m.DataRate
+	}
+	return ""
+}
+
+func (m *Metadata) GetFrequency() float32 {
+	if m != nil {
+		return m.Frequency
+	}
+	return 0
+}
+
+func (m *Metadata) GetGateways() []*GatewayMetadata {
+	if m != nil {
+		return m.Gateways
+	}
+	return nil
+}
+
+func (m *Metadata) GetEstimated() bool {
+	if m != nil {
+		return m.Estimated
+	}
+	return false
+}
+
+func (m *Metadata) GetAccuracy() float64 {
+	if m != nil {
+		return m.Accuracy
+	}
+	return 0
+}
+
+func (m *Metadata) GetDecoder() string {
+	if m != nil {
+		return m.Decoder
+	}
+	return ""
+}
+
+// GatewayMetadata is the reception of an uplink by a gateway
+type GatewayMetadata struct {
+	GatewayId string `protobuf:"bytes,1,opt,name=gateway_id,json=gatewayId,proto3" json:"gateway_id,omitempty"`
+	// in dBm
+	Rssi float32 `protobuf:"fixed32,2,opt,name=rssi,proto3" json:"rssi,omitempty"`
+	// in dB
+	Snr float32 `protobuf:"fixed32,3,opt,name=snr,proto3" json:"snr,omitempty"`
+	// the gateway location if known
+	Latitude             float64  `protobuf:"fixed64,4,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude            float64  `protobuf:"fixed64,5,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Altitude             int32    `protobuf:"varint,6,opt,name=altitude,proto3" json:"altitude,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GatewayMetadata) Reset()         { *m = GatewayMetadata{} }
+func (m *GatewayMetadata) String() string { return proto.CompactTextString(m) }
+func (*GatewayMetadata) ProtoMessage()    {}
+func (*GatewayMetadata) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{2}
+}
+
+func (m *GatewayMetadata) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GatewayMetadata.Unmarshal(m, b)
+}
+func (m *GatewayMetadata) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GatewayMetadata.Marshal(b, m, deterministic)
+}
+func (m *GatewayMetadata) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GatewayMetadata.Merge(m, src)
+}
+func (m *GatewayMetadata) XXX_Size() int {
+	return xxx_messageInfo_GatewayMetadata.Size(m)
+}
+func (m *GatewayMetadata) XXX_DiscardUnknown() {
+	xxx_messageInfo_GatewayMetadata.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GatewayMetadata proto.InternalMessageInfo
+
+func (m *GatewayMetadata) GetGatewayId() string {
+	if m != nil {
+		return m.GatewayId
+	}
+	return ""
+}
+
+func (m *GatewayMetadata) GetRssi() float32 {
+	if m != nil {
+		return m.Rssi
+	}
+	return 0
+}
+
+func (m *GatewayMetadata) GetSnr() float32 {
+	if m != nil {
+		return m.Snr
+	}
+	return 0
+}
+
+func (m *GatewayMetadata) GetLatitude() float64 {
+	if m != nil {
+		return m.Latitude
+	}
+	return 0
+}
+
+func (m *GatewayMetadata) GetLongitude() float64 {
+	if m != nil {
+		return m.Longitude
+	}
+	return 0
+}
+
+func (m *GatewayMetadata) GetAltitude() int32 {
+	if m != nil {
+		return m.Altitude
+	}
+	return 0
+}
+
 type KeyList struct {
 	Keys                 []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -114,16 +346,17 @@ func (m *KeyList) Reset()         { *m = KeyList{} }
 func (m *KeyList) String() string { return proto.CompactTextString(m) }
 func (*KeyList) ProtoMessage()    {}
 func (*KeyList) Descriptor() ([]byte, []int) {
-	return fileDescriptor_geottnsvc_d04c6f621b8ce5f8, []int{1}
+	return fileDescriptor_06af7e6094328dd5, []int{3}
 }
+
 func (m *KeyList) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_KeyList.Unmarshal(m, b)
 }
 func (m *KeyList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	return xxx_messageInfo_KeyList.Marshal(b, m, deterministic)
 }
-func (dst *KeyList) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_KeyList.Merge(dst, src)
+func (m *KeyList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyList.Merge(m, src)
 }
 func (m *KeyList) XXX_Size() int {
 	return xxx_messageInfo_KeyList.Size(m)
@@ -141,27 +374,175 @@ func (m *KeyList) GetKeys() []string {
 	return nil
 }
 
-type DataPoints struct {
+type StoreBatchRequest struct {
 	Points               []*DataPoint `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
 	XXX_unrecognized     []byte       `json:"-"`
 	XXX_sizecache        int32        `json:"-"`
 }
 
+func (m *StoreBatchRequest) Reset()         { *m = StoreBatchRequest{} }
+func (m *StoreBatchRequest) String() string { return proto.CompactTextString(m) }
+func (*StoreBatchRequest) ProtoMessage()    {}
+func (*StoreBatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{4}
+}
+
+func (m *StoreBatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StoreBatchRequest.Unmarshal(m, b)
+}
+func (m *StoreBatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StoreBatchRequest.Marshal(b, m, deterministic)
+}
+func (m *StoreBatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StoreBatchRequest.Merge(m, src)
+}
+func (m *StoreBatchRequest) XXX_Size() int {
+	return xxx_messageInfo_StoreBatchRequest.Size(m)
+}
+func (m *StoreBatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StoreBatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StoreBatchRequest proto.InternalMessageInfo
+
+func (m *StoreBatchRequest) GetPoints() []*DataPoint {
+	if m != nil {
+		return m.Points
+	}
+	return nil
+}
+
+// StoreSummary reports the outcome of a batch or a stream of points to store
+type StoreSummary struct {
+	Stored int64 `protobuf:"varint,1,opt,name=stored,proto3" json:"stored,omitempty"`
+	// points not stored, invalid (missing device id, out of range position or time), past the retention or failing
+	Rejected             int64         `protobuf:"varint,2,opt,name=rejected,proto3" json:"rejected,omitempty"`
+	Errors               []*StoreError `protobuf:"bytes,3,rep,name=errors,proto3" json:"errors,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *StoreSummary) Reset()         { *m = StoreSummary{} }
+func (m *StoreSummary) String() string { return proto.CompactTextString(m) }
+func (*StoreSummary) ProtoMessage()    {}
+func (*StoreSummary) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{5}
+}
+
+func (m *StoreSummary) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StoreSummary.Unmarshal(m, b)
+}
+func (m *StoreSummary) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StoreSummary.Marshal(b, m, deterministic)
+}
+func (m *StoreSummary) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StoreSummary.Merge(m, src)
+}
+func (m *StoreSummary) XXX_Size() int {
+	return xxx_messageInfo_StoreSummary.Size(m)
+}
+func (m *StoreSummary) XXX_DiscardUnknown() {
+	xxx_messageInfo_StoreSummary.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StoreSummary proto.InternalMessageInfo
+
+func (m *StoreSummary) GetStored() int64 {
+	if m != nil {
+		return m.Stored
+	}
+	return 0
+}
+
+func (m *StoreSummary) GetRejected() int64 {
+	if m != nil {
+		return m.Rejected
+	}
+	return 0
+}
+
+func (m *StoreSummary) GetErrors() []*StoreError {
+	if m != nil {
+		return m.Errors
+	}
+	return nil
+}
+
+// StoreError is the reason a point was rejected
+type StoreError struct {
+	// index of the point in the batch or the stream
+	Index                int64    `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StoreError) Reset()         { *m = StoreError{} }
+func (m *StoreError) String() string { return proto.CompactTextString(m) }
+func (*StoreError) ProtoMessage()    {}
+func (*StoreError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{6}
+}
+
+func (m *StoreError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StoreError.Unmarshal(m, b)
+}
+func (m *StoreError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StoreError.Marshal(b, m, deterministic)
+}
+func (m *StoreError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StoreError.Merge(m, src)
+}
+func (m *StoreError) XXX_Size() int {
+	return xxx_messageInfo_StoreError.Size(m)
+}
+func (m *StoreError) XXX_DiscardUnknown() {
+	xxx_messageInfo_StoreError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StoreError proto.InternalMessageInfo
+
+func (m *StoreError) GetIndex() int64 {
+	if m != nil {
+		return m.Index
+	}
+	return 0
+}
+
+func (m *StoreError) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+type DataPoints struct {
+	Points []*DataPoint `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
+	// opaque token to query the next page, empty when there are no more results
+	NextCursor           []byte   `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
 func (m *DataPoints) Reset()         { *m = DataPoints{} }
 func (m *DataPoints) String() string { return proto.CompactTextString(m) }
 func (*DataPoints) ProtoMessage()    {}
 func (*DataPoints) Descriptor() ([]byte, []int) {
-	return fileDescriptor_geottnsvc_d04c6f621b8ce5f8, []int{2}
+	return fileDescriptor_06af7e6094328dd5, []int{7}
 }
+
 func (m *DataPoints) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DataPoints.Unmarshal(m, b)
 }
 func (m *DataPoints) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	return xxx_messageInfo_DataPoints.Marshal(b, m, deterministic)
 }
-func (dst *DataPoints) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_DataPoints.Merge(dst, src)
+func (m *DataPoints) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DataPoints.Merge(m, src)
 }
 func (m *DataPoints) XXX_Size() int {
 	return xxx_messageInfo_DataPoints.Size(m)
@@ -179,6 +560,13 @@ func (m *DataPoints) GetPoints() []*DataPoint {
 	return nil
 }
 
+func (m *DataPoints) GetNextCursor() []byte {
+	if m != nil {
+		return m.NextCursor
+	}
+	return nil
+}
+
 type GetRequest struct {
 	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -190,16 +578,17 @@ func (m *GetRequest) Reset()         { *m = GetRequest{} }
 func (m *GetRequest) String() string { return proto.CompactTextString(m) }
 func (*GetRequest) ProtoMessage()    {}
 func (*GetRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_geottnsvc_d04c6f621b8ce5f8, []int{3}
+	return fileDescriptor_06af7e6094328dd5, []int{8}
 }
+
 func (m *GetRequest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_GetRequest.Unmarshal(m, b)
 }
 func (m *GetRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
 	return xxx_messageInfo_GetRequest.Marshal(b, m, deterministic)
 }
-func (dst *GetRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetRequest.Merge(dst, src)
+func (m *GetRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetRequest.Merge(m, src)
 }
 func (m *GetRequest) XXX_Size() int {
 	return xxx_messageInfo_GetRequest.Size(m)
@@ -217,130 +606,1282 @@ func (m *GetRequest) GetKey() string {
 	return ""
 }
 
-type RadiusSearchRequest struct {
-	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
-	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
-	// radius in meters
-	Radius               float64  `protobuf:"fixed64,3,opt,name=radius,proto3" json:"radius,omitempty"`
+type DeleteRequest struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *RadiusSearchRequest) Reset()         { *m = RadiusSearchRequest{} }
-func (m *RadiusSearchRequest) String() string { return proto.CompactTextString(m) }
-func (*RadiusSearchRequest) ProtoMessage()    {}
-func (*RadiusSearchRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_geottnsvc_d04c6f621b8ce5f8, []int{4}
-}
-func (m *RadiusSearchRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_RadiusSearchRequest.Unmarshal(m, b)
+func (m *DeleteRequest) Reset()         { *m = DeleteRequest{} }
+func (m *DeleteRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteRequest) ProtoMessage()    {}
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{9}
 }
-func (m *RadiusSearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_RadiusSearchRequest.Marshal(b, m, deterministic)
+
+func (m *DeleteRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteRequest.Unmarshal(m, b)
 }
-func (dst *RadiusSearchRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_RadiusSearchRequest.Merge(dst, src)
+func (m *DeleteRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteRequest.Marshal(b, m, deterministic)
 }
-func (m *RadiusSearchRequest) XXX_Size() int {
-	return xxx_messageInfo_RadiusSearchRequest.Size(m)
+func (m *DeleteRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteRequest.Merge(m, src)
 }
-func (m *RadiusSearchRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_RadiusSearchRequest.DiscardUnknown(m)
+func (m *DeleteRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteRequest.Size(m)
 }
-
-var xxx_messageInfo_RadiusSearchRequest proto.InternalMessageInfo
-
-func (m *RadiusSearchRequest) GetLat() float64 {
-	if m != nil {
-		return m.Lat
-	}
-	return 0
+func (m *DeleteRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteRequest.DiscardUnknown(m)
 }
 
-func (m *RadiusSearchRequest) GetLng() float64 {
-	if m != nil {
-		return m.Lng
-	}
-	return 0
-}
+var xxx_messageInfo_DeleteRequest proto.InternalMessageInfo
 
-func (m *RadiusSearchRequest) GetRadius() float64 {
+func (m *DeleteRequest) GetKey() string {
 	if m != nil {
-		return m.Radius
+		return m.Key
 	}
-	return 0
+	return ""
 }
 
-type RectSearchRequest struct {
-	Urlat                float64  `protobuf:"fixed64,1,opt,name=urlat,proto3" json:"urlat,omitempty"`
-	Urlng                float64  `protobuf:"fixed64,2,opt,name=urlng,proto3" json:"urlng,omitempty"`
-	Bllat                float64  `protobuf:"fixed64,3,opt,name=bllat,proto3" json:"bllat,omitempty"`
-	Bllng                float64  `protobuf:"fixed64,4,opt,name=bllng,proto3" json:"bllng,omitempty"`
+type GetAllRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// optional time range, unbounded if not set
+	Start *timestamp.Timestamp `protobuf:"bytes,2,opt,name=start,proto3" json:"start,omitempty"`
+	End   *timestamp.Timestamp `protobuf:"bytes,3,opt,name=end,proto3" json:"end,omitempty"`
+	// max number of points to return, 0 for no limit
+	Count int32 `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	// next_cursor from a previous response to query the next page
+	Cursor []byte `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// returns the oldest points first instead of the most recent
+	OldestFirst          bool     `protobuf:"varint,6,opt,name=oldest_first,json=oldestFirst,proto3" json:"oldest_first,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *RectSearchRequest) Reset()         { *m = RectSearchRequest{} }
-func (m *RectSearchRequest) String() string { return proto.CompactTextString(m) }
-func (*RectSearchRequest) ProtoMessage()    {}
-func (*RectSearchRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_geottnsvc_d04c6f621b8ce5f8, []int{5}
+func (m *GetAllRequest) Reset()         { *m = GetAllRequest{} }
+func (m *GetAllRequest) String() string { return proto.CompactTextString(m) }
+func (*GetAllRequest) ProtoMessage()    {}
+func (*GetAllRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{10}
 }
-func (m *RectSearchRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_RectSearchRequest.Unmarshal(m, b)
+
+func (m *GetAllRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetAllRequest.Unmarshal(m, b)
 }
-func (m *RectSearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_RectSearchRequest.Marshal(b, m, deterministic)
+func (m *GetAllRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetAllRequest.Marshal(b, m, deterministic)
 }
-func (dst *RectSearchRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_RectSearchRequest.Merge(dst, src)
+func (m *GetAllRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetAllRequest.Merge(m, src)
 }
-func (m *RectSearchRequest) XXX_Size() int {
-	return xxx_messageInfo_RectSearchRequest.Size(m)
+func (m *GetAllRequest) XXX_Size() int {
+	return xxx_messageInfo_GetAllRequest.Size(m)
+}
+func (m *GetAllRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetAllRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetAllRequest proto.InternalMessageInfo
+
+func (m *GetAllRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GetAllRequest) GetStart() *timestamp.Timestamp {
+	if m != nil {
+		return m.Start
+	}
+	return nil
+}
+
+func (m *GetAllRequest) GetEnd() *timestamp.Timestamp {
+	if m != nil {
+		return m.End
+	}
+	return nil
+}
+
+func (m *GetAllRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *GetAllRequest) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+func (m *GetAllRequest) GetOldestFirst() bool {
+	if m != nil {
+		return m.OldestFirst
+	}
+	return false
+}
+
+type RadiusSearchRequest struct {
+	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	// radius in meters
+	Radius float64 `protobuf:"fixed64,3,opt,name=radius,proto3" json:"radius,omitempty"`
+	// max number of points to return, 0 for no limit
+	Count int32 `protobuf:"varint,4,opt,name=count,proto3" json:"count,omitempty"`
+	// next_cursor from a previous response to query the next page
+	Cursor               []byte   `protobuf:"bytes,5,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RadiusSearchRequest) Reset()         { *m = RadiusSearchRequest{} }
+func (m *RadiusSearchRequest) String() string { return proto.CompactTextString(m) }
+func (*RadiusSearchRequest) ProtoMessage()    {}
+func (*RadiusSearchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{11}
+}
+
+func (m *RadiusSearchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RadiusSearchRequest.Unmarshal(m, b)
+}
+func (m *RadiusSearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RadiusSearchRequest.Marshal(b, m, deterministic)
+}
+func (m *RadiusSearchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RadiusSearchRequest.Merge(m, src)
+}
+func (m *RadiusSearchRequest) XXX_Size() int {
+	return xxx_messageInfo_RadiusSearchRequest.Size(m)
+}
+func (m *RadiusSearchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RadiusSearchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RadiusSearchRequest proto.InternalMessageInfo
+
+func (m *RadiusSearchRequest) GetLat() float64 {
+	if m != nil {
+		return m.Lat
+	}
+	return 0
+}
+
+func (m *RadiusSearchRequest) GetLng() float64 {
+	if m != nil {
+		return m.Lng
+	}
+	return 0
+}
+
+func (m *RadiusSearchRequest) GetRadius() float64 {
+	if m != nil {
+		return m.Radius
+	}
+	return 0
+}
+
+func (m *RadiusSearchRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *RadiusSearchRequest) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+type RectSearchRequest struct {
+	Urlat float64 `protobuf:"fixed64,1,opt,name=urlat,proto3" json:"urlat,omitempty"`
+	Urlng float64 `protobuf:"fixed64,2,opt,name=urlng,proto3" json:"urlng,omitempty"`
+	Bllat float64 `protobuf:"fixed64,3,opt,name=bllat,proto3" json:"bllat,omitempty"`
+	Bllng float64 `protobuf:"fixed64,4,opt,name=bllng,proto3" json:"bllng,omitempty"`
+	// max number of points to return, 0 for no limit
+	Count int32 `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	// next_cursor from a previous response to query the next page
+	Cursor               []byte   `protobuf:"bytes,6,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RectSearchRequest) Reset()         { *m = RectSearchRequest{} }
+func (m *RectSearchRequest) String() string { return proto.CompactTextString(m) }
+func (*RectSearchRequest) ProtoMessage()    {}
+func (*RectSearchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{12}
+}
+
+func (m *RectSearchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RectSearchRequest.Unmarshal(m, b)
+}
+func (m *RectSearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RectSearchRequest.Marshal(b, m, deterministic)
+}
+func (m *RectSearchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RectSearchRequest.Merge(m, src)
+}
+func (m *RectSearchRequest) XXX_Size() int {
+	return xxx_messageInfo_RectSearchRequest.Size(m)
 }
 func (m *RectSearchRequest) XXX_DiscardUnknown() {
 	xxx_messageInfo_RectSearchRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_RectSearchRequest proto.InternalMessageInfo
+var xxx_messageInfo_RectSearchRequest proto.InternalMessageInfo
+
+func (m *RectSearchRequest) GetUrlat() float64 {
+	if m != nil {
+		return m.Urlat
+	}
+	return 0
+}
+
+func (m *RectSearchRequest) GetUrlng() float64 {
+	if m != nil {
+		return m.Urlng
+	}
+	return 0
+}
+
+func (m *RectSearchRequest) GetBllat() float64 {
+	if m != nil {
+		return m.Bllat
+	}
+	return 0
+}
+
+func (m *RectSearchRequest) GetBllng() float64 {
+	if m != nil {
+		return m.Bllng
+	}
+	return 0
+}
+
+func (m *RectSearchRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *RectSearchRequest) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+type NearestSearchRequest struct {
+	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	// number of points to return
+	Count int32 `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	// max distance in meters, 0 for no limit
+	MaxDistance          float64  `protobuf:"fixed64,4,opt,name=max_distance,json=maxDistance,proto3" json:"max_distance,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NearestSearchRequest) Reset()         { *m = NearestSearchRequest{} }
+func (m *NearestSearchRequest) String() string { return proto.CompactTextString(m) }
+func (*NearestSearchRequest) ProtoMessage()    {}
+func (*NearestSearchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{13}
+}
+
+func (m *NearestSearchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NearestSearchRequest.Unmarshal(m, b)
+}
+func (m *NearestSearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NearestSearchRequest.Marshal(b, m, deterministic)
+}
+func (m *NearestSearchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NearestSearchRequest.Merge(m, src)
+}
+func (m *NearestSearchRequest) XXX_Size() int {
+	return xxx_messageInfo_NearestSearchRequest.Size(m)
+}
+func (m *NearestSearchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_NearestSearchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NearestSearchRequest proto.InternalMessageInfo
+
+func (m *NearestSearchRequest) GetLat() float64 {
+	if m != nil {
+		return m.Lat
+	}
+	return 0
+}
+
+func (m *NearestSearchRequest) GetLng() float64 {
+	if m != nil {
+		return m.Lng
+	}
+	return 0
+}
+
+func (m *NearestSearchRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *NearestSearchRequest) GetMaxDistance() float64 {
+	if m != nil {
+		return m.MaxDistance
+	}
+	return 0
+}
+
+// WatchRequest filters the watched points, by devices, region or both
+type WatchRequest struct {
+	// only watch these devices, all if empty
+	Keys []string `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	// only watch inside one of these regions if set, count and cursor are ignored
+	Radius               *RadiusSearchRequest `protobuf:"bytes,2,opt,name=radius,proto3" json:"radius,omitempty"`
+	Rect                 *RectSearchRequest   `protobuf:"bytes,3,opt,name=rect,proto3" json:"rect,omitempty"`
+	Polygon              *Polygon             `protobuf:"bytes,4,opt,name=polygon,proto3" json:"polygon,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *WatchRequest) Reset()         { *m = WatchRequest{} }
+func (m *WatchRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchRequest) ProtoMessage()    {}
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{14}
+}
+
+func (m *WatchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchRequest.Unmarshal(m, b)
+}
+func (m *WatchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchRequest.Marshal(b, m, deterministic)
+}
+func (m *WatchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchRequest.Merge(m, src)
+}
+func (m *WatchRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchRequest.Size(m)
+}
+func (m *WatchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchRequest proto.InternalMessageInfo
+
+func (m *WatchRequest) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+func (m *WatchRequest) GetRadius() *RadiusSearchRequest {
+	if m != nil {
+		return m.Radius
+	}
+	return nil
+}
+
+func (m *WatchRequest) GetRect() *RectSearchRequest {
+	if m != nil {
+		return m.Rect
+	}
+	return nil
+}
+
+func (m *WatchRequest) GetPolygon() *Polygon {
+	if m != nil {
+		return m.Polygon
+	}
+	return nil
+}
+
+type LatLng struct {
+	Lat                  float64  `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng                  float64  `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LatLng) Reset()         { *m = LatLng{} }
+func (m *LatLng) String() string { return proto.CompactTextString(m) }
+func (*LatLng) ProtoMessage()    {}
+func (*LatLng) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{15}
+}
+
+func (m *LatLng) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LatLng.Unmarshal(m, b)
+}
+func (m *LatLng) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LatLng.Marshal(b, m, deterministic)
+}
+func (m *LatLng) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LatLng.Merge(m, src)
+}
+func (m *LatLng) XXX_Size() int {
+	return xxx_messageInfo_LatLng.Size(m)
+}
+func (m *LatLng) XXX_DiscardUnknown() {
+	xxx_messageInfo_LatLng.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LatLng proto.InternalMessageInfo
+
+func (m *LatLng) GetLat() float64 {
+	if m != nil {
+		return m.Lat
+	}
+	return 0
+}
+
+func (m *LatLng) GetLng() float64 {
+	if m != nil {
+		return m.Lng
+	}
+	return 0
+}
+
+type Ring struct {
+	Points               []*LatLng `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *Ring) Reset()         { *m = Ring{} }
+func (m *Ring) String() string { return proto.CompactTextString(m) }
+func (*Ring) ProtoMessage()    {}
+func (*Ring) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{16}
+}
+
+func (m *Ring) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Ring.Unmarshal(m, b)
+}
+func (m *Ring) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Ring.Marshal(b, m, deterministic)
+}
+func (m *Ring) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Ring.Merge(m, src)
+}
+func (m *Ring) XXX_Size() int {
+	return xxx_messageInfo_Ring.Size(m)
+}
+func (m *Ring) XXX_DiscardUnknown() {
+	xxx_messageInfo_Ring.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Ring proto.InternalMessageInfo
+
+func (m *Ring) GetPoints() []*LatLng {
+	if m != nil {
+		return m.Points
+	}
+	return nil
+}
+
+// Polygon shells and holes are found by their nesting, multiple shells can be passed
+type Polygon struct {
+	Rings                []*Ring  `protobuf:"bytes,1,rep,name=rings,proto3" json:"rings,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Polygon) Reset()         { *m = Polygon{} }
+func (m *Polygon) String() string { return proto.CompactTextString(m) }
+func (*Polygon) ProtoMessage()    {}
+func (*Polygon) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{17}
+}
+
+func (m *Polygon) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Polygon.Unmarshal(m, b)
+}
+func (m *Polygon) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Polygon.Marshal(b, m, deterministic)
+}
+func (m *Polygon) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Polygon.Merge(m, src)
+}
+func (m *Polygon) XXX_Size() int {
+	return xxx_messageInfo_Polygon.Size(m)
+}
+func (m *Polygon) XXX_DiscardUnknown() {
+	xxx_messageInfo_Polygon.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Polygon proto.InternalMessageInfo
+
+func (m *Polygon) GetRings() []*Ring {
+	if m != nil {
+		return m.Rings
+	}
+	return nil
+}
+
+type PolygonSearchRequest struct {
+	Polygon *Polygon `protobuf:"bytes,1,opt,name=polygon,proto3" json:"polygon,omitempty"`
+	// max number of points to return, 0 for no limit
+	Count int32 `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	// next_cursor from a previous response to query the next page
+	Cursor               []byte   `protobuf:"bytes,3,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PolygonSearchRequest) Reset()         { *m = PolygonSearchRequest{} }
+func (m *PolygonSearchRequest) String() string { return proto.CompactTextString(m) }
+func (*PolygonSearchRequest) ProtoMessage()    {}
+func (*PolygonSearchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{18}
+}
+
+func (m *PolygonSearchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PolygonSearchRequest.Unmarshal(m, b)
+}
+func (m *PolygonSearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PolygonSearchRequest.Marshal(b, m, deterministic)
+}
+func (m *PolygonSearchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PolygonSearchRequest.Merge(m, src)
+}
+func (m *PolygonSearchRequest) XXX_Size() int {
+	return xxx_messageInfo_PolygonSearchRequest.Size(m)
+}
+func (m *PolygonSearchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PolygonSearchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PolygonSearchRequest proto.InternalMessageInfo
+
+func (m *PolygonSearchRequest) GetPolygon() *Polygon {
+	if m != nil {
+		return m.Polygon
+	}
+	return nil
+}
+
+func (m *PolygonSearchRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *PolygonSearchRequest) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+type RadiusHistorySearchRequest struct {
+	Lat float64 `protobuf:"fixed64,1,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng float64 `protobuf:"fixed64,2,opt,name=lng,proto3" json:"lng,omitempty"`
+	// radius in meters
+	Radius float64              `protobuf:"fixed64,3,opt,name=radius,proto3" json:"radius,omitempty"`
+	Start  *timestamp.Timestamp `protobuf:"bytes,4,opt,name=start,proto3" json:"start,omitempty"`
+	End    *timestamp.Timestamp `protobuf:"bytes,5,opt,name=end,proto3" json:"end,omitempty"`
+	// max number of points to return, 0 for no limit
+	Count int32 `protobuf:"varint,6,opt,name=count,proto3" json:"count,omitempty"`
+	// next_cursor from a previous response to query the next page
+	Cursor               []byte   `protobuf:"bytes,7,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RadiusHistorySearchRequest) Reset()         { *m = RadiusHistorySearchRequest{} }
+func (m *RadiusHistorySearchRequest) String() string { return proto.CompactTextString(m) }
+func (*RadiusHistorySearchRequest) ProtoMessage()    {}
+func (*RadiusHistorySearchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{19}
+}
+
+func (m *RadiusHistorySearchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RadiusHistorySearchRequest.Unmarshal(m, b)
+}
+func (m *RadiusHistorySearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RadiusHistorySearchRequest.Marshal(b, m, deterministic)
+}
+func (m *RadiusHistorySearchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RadiusHistorySearchRequest.Merge(m, src)
+}
+func (m *RadiusHistorySearchRequest) XXX_Size() int {
+	return xxx_messageInfo_RadiusHistorySearchRequest.Size(m)
+}
+func (m *RadiusHistorySearchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RadiusHistorySearchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RadiusHistorySearchRequest proto.InternalMessageInfo
+
+func (m *RadiusHistorySearchRequest) GetLat() float64 {
+	if m != nil {
+		return m.Lat
+	}
+	return 0
+}
+
+func (m *RadiusHistorySearchRequest) GetLng() float64 {
+	if m != nil {
+		return m.Lng
+	}
+	return 0
+}
+
+func (m *RadiusHistorySearchRequest) GetRadius() float64 {
+	if m != nil {
+		return m.Radius
+	}
+	return 0
+}
+
+func (m *RadiusHistorySearchRequest) GetStart() *timestamp.Timestamp {
+	if m != nil {
+		return m.Start
+	}
+	return nil
+}
+
+func (m *RadiusHistorySearchRequest) GetEnd() *timestamp.Timestamp {
+	if m != nil {
+		return m.End
+	}
+	return nil
+}
+
+func (m *RadiusHistorySearchRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *RadiusHistorySearchRequest) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+type RectHistorySearchRequest struct {
+	Urlat float64              `protobuf:"fixed64,1,opt,name=urlat,proto3" json:"urlat,omitempty"`
+	Urlng float64              `protobuf:"fixed64,2,opt,name=urlng,proto3" json:"urlng,omitempty"`
+	Bllat float64              `protobuf:"fixed64,3,opt,name=bllat,proto3" json:"bllat,omitempty"`
+	Bllng float64              `protobuf:"fixed64,4,opt,name=bllng,proto3" json:"bllng,omitempty"`
+	Start *timestamp.Timestamp `protobuf:"bytes,5,opt,name=start,proto3" json:"start,omitempty"`
+	End   *timestamp.Timestamp `protobuf:"bytes,6,opt,name=end,proto3" json:"end,omitempty"`
+	// max number of points to return, 0 for no limit
+	Count int32 `protobuf:"varint,7,opt,name=count,proto3" json:"count,omitempty"`
+	// next_cursor from a previous response to query the next page
+	Cursor               []byte   `protobuf:"bytes,8,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RectHistorySearchRequest) Reset()         { *m = RectHistorySearchRequest{} }
+func (m *RectHistorySearchRequest) String() string { return proto.CompactTextString(m) }
+func (*RectHistorySearchRequest) ProtoMessage()    {}
+func (*RectHistorySearchRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{20}
+}
+
+func (m *RectHistorySearchRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RectHistorySearchRequest.Unmarshal(m, b)
+}
+func (m *RectHistorySearchRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RectHistorySearchRequest.Marshal(b, m, deterministic)
+}
+func (m *RectHistorySearchRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RectHistorySearchRequest.Merge(m, src)
+}
+func (m *RectHistorySearchRequest) XXX_Size() int {
+	return xxx_messageInfo_RectHistorySearchRequest.Size(m)
+}
+func (m *RectHistorySearchRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RectHistorySearchRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RectHistorySearchRequest proto.InternalMessageInfo
+
+func (m *RectHistorySearchRequest) GetUrlat() float64 {
+	if m != nil {
+		return m.Urlat
+	}
+	return 0
+}
+
+func (m *RectHistorySearchRequest) GetUrlng() float64 {
+	if m != nil {
+		return m.Urlng
+	}
+	return 0
+}
+
+func (m *RectHistorySearchRequest) GetBllat() float64 {
+	if m != nil {
+		return m.Bllat
+	}
+	return 0
+}
+
+func (m *RectHistorySearchRequest) GetBllng() float64 {
+	if m != nil {
+		return m.Bllng
+	}
+	return 0
+}
+
+func (m *RectHistorySearchRequest) GetStart() *timestamp.Timestamp {
+	if m != nil {
+		return m.Start
+	}
+	return nil
+}
+
+func (m *RectHistorySearchRequest) GetEnd() *timestamp.Timestamp {
+	if m != nil {
+		return m.End
+	}
+	return nil
+}
+
+func (m *RectHistorySearchRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *RectHistorySearchRequest) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+// Geofence is a circle or a polygon if set
+type Geofence struct {
+	Id   string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	// circle center and radius in meters
+	Lat                  float64  `protobuf:"fixed64,3,opt,name=lat,proto3" json:"lat,omitempty"`
+	Lng                  float64  `protobuf:"fixed64,4,opt,name=lng,proto3" json:"lng,omitempty"`
+	Radius               float64  `protobuf:"fixed64,5,opt,name=radius,proto3" json:"radius,omitempty"`
+	Polygon              *Polygon `protobuf:"bytes,6,opt,name=polygon,proto3" json:"polygon,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Geofence) Reset()         { *m = Geofence{} }
+func (m *Geofence) String() string { return proto.CompactTextString(m) }
+func (*Geofence) ProtoMessage()    {}
+func (*Geofence) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{21}
+}
+
+func (m *Geofence) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Geofence.Unmarshal(m, b)
+}
+func (m *Geofence) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Geofence.Marshal(b, m, deterministic)
+}
+func (m *Geofence) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Geofence.Merge(m, src)
+}
+func (m *Geofence) XXX_Size() int {
+	return xxx_messageInfo_Geofence.Size(m)
+}
+func (m *Geofence) XXX_DiscardUnknown() {
+	xxx_messageInfo_Geofence.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Geofence proto.InternalMessageInfo
+
+func (m *Geofence) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *Geofence) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Geofence) GetLat() float64 {
+	if m != nil {
+		return m.Lat
+	}
+	return 0
+}
+
+func (m *Geofence) GetLng() float64 {
+	if m != nil {
+		return m.Lng
+	}
+	return 0
+}
+
+func (m *Geofence) GetRadius() float64 {
+	if m != nil {
+		return m.Radius
+	}
+	return 0
+}
+
+func (m *Geofence) GetPolygon() *Polygon {
+	if m != nil {
+		return m.Polygon
+	}
+	return nil
+}
+
+type Geofences struct {
+	Geofences            []*Geofence `protobuf:"bytes,1,rep,name=geofences,proto3" json:"geofences,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *Geofences) Reset()         { *m = Geofences{} }
+func (m *Geofences) String() string { return proto.CompactTextString(m) }
+func (*Geofences) ProtoMessage()    {}
+func (*Geofences) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{22}
+}
+
+func (m *Geofences) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Geofences.Unmarshal(m, b)
+}
+func (m *Geofences) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Geofences.Marshal(b, m, deterministic)
+}
+func (m *Geofences) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Geofences.Merge(m, src)
+}
+func (m *Geofences) XXX_Size() int {
+	return xxx_messageInfo_Geofences.Size(m)
+}
+func (m *Geofences) XXX_DiscardUnknown() {
+	xxx_messageInfo_Geofences.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Geofences proto.InternalMessageInfo
+
+func (m *Geofences) GetGeofences() []*Geofence {
+	if m != nil {
+		return m.Geofences
+	}
+	return nil
+}
+
+type GeofenceRequest struct {
+	Id                   string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
 
-func (m *RectSearchRequest) GetUrlat() float64 {
+func (m *GeofenceRequest) Reset()         { *m = GeofenceRequest{} }
+func (m *GeofenceRequest) String() string { return proto.CompactTextString(m) }
+func (*GeofenceRequest) ProtoMessage()    {}
+func (*GeofenceRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{23}
+}
+
+func (m *GeofenceRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GeofenceRequest.Unmarshal(m, b)
+}
+func (m *GeofenceRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GeofenceRequest.Marshal(b, m, deterministic)
+}
+func (m *GeofenceRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GeofenceRequest.Merge(m, src)
+}
+func (m *GeofenceRequest) XXX_Size() int {
+	return xxx_messageInfo_GeofenceRequest.Size(m)
+}
+func (m *GeofenceRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GeofenceRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GeofenceRequest proto.InternalMessageInfo
+
+func (m *GeofenceRequest) GetId() string {
 	if m != nil {
-		return m.Urlat
+		return m.Id
 	}
-	return 0
+	return ""
 }
 
-func (m *RectSearchRequest) GetUrlng() float64 {
+type GeofenceEvent struct {
+	FenceId              string               `protobuf:"bytes,1,opt,name=fence_id,json=fenceId,proto3" json:"fence_id,omitempty"`
+	DeviceId             string               `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	Type                 GeofenceEvent_Type   `protobuf:"varint,3,opt,name=type,proto3,enum=GeofenceEvent_Type" json:"type,omitempty"`
+	Time                 *timestamp.Timestamp `protobuf:"bytes,4,opt,name=time,proto3" json:"time,omitempty"`
+	Latitude             float64              `protobuf:"fixed64,5,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude            float64              `protobuf:"fixed64,6,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *GeofenceEvent) Reset()         { *m = GeofenceEvent{} }
+func (m *GeofenceEvent) String() string { return proto.CompactTextString(m) }
+func (*GeofenceEvent) ProtoMessage()    {}
+func (*GeofenceEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{24}
+}
+
+func (m *GeofenceEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GeofenceEvent.Unmarshal(m, b)
+}
+func (m *GeofenceEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GeofenceEvent.Marshal(b, m, deterministic)
+}
+func (m *GeofenceEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GeofenceEvent.Merge(m, src)
+}
+func (m *GeofenceEvent) XXX_Size() int {
+	return xxx_messageInfo_GeofenceEvent.Size(m)
+}
+func (m *GeofenceEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_GeofenceEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GeofenceEvent proto.InternalMessageInfo
+
+func (m *GeofenceEvent) GetFenceId() string {
 	if m != nil {
-		return m.Urlng
+		return m.FenceId
+	}
+	return ""
+}
+
+func (m *GeofenceEvent) GetDeviceId() string {
+	if m != nil {
+		return m.DeviceId
+	}
+	return ""
+}
+
+func (m *GeofenceEvent) GetType() GeofenceEvent_Type {
+	if m != nil {
+		return m.Type
+	}
+	return GeofenceEvent_UNKNOWN
+}
+
+func (m *GeofenceEvent) GetTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.Time
+	}
+	return nil
+}
+
+func (m *GeofenceEvent) GetLatitude() float64 {
+	if m != nil {
+		return m.Latitude
 	}
 	return 0
 }
 
-func (m *RectSearchRequest) GetBllat() float64 {
+func (m *GeofenceEvent) GetLongitude() float64 {
 	if m != nil {
-		return m.Bllat
+		return m.Longitude
 	}
 	return 0
 }
 
-func (m *RectSearchRequest) GetBllng() float64 {
+// GeofenceEventsRequest queries the events of a fence or of a device
+type GeofenceEventsRequest struct {
+	FenceId  string `protobuf:"bytes,1,opt,name=fence_id,json=fenceId,proto3" json:"fence_id,omitempty"`
+	DeviceId string `protobuf:"bytes,2,opt,name=device_id,json=deviceId,proto3" json:"device_id,omitempty"`
+	// optional time range, unbounded if not set
+	Start *timestamp.Timestamp `protobuf:"bytes,3,opt,name=start,proto3" json:"start,omitempty"`
+	End   *timestamp.Timestamp `protobuf:"bytes,4,opt,name=end,proto3" json:"end,omitempty"`
+	// max number of events to return, 0 for no limit
+	Count int32 `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	// next_cursor from a previous response to query the next page
+	Cursor               []byte   `protobuf:"bytes,6,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GeofenceEventsRequest) Reset()         { *m = GeofenceEventsRequest{} }
+func (m *GeofenceEventsRequest) String() string { return proto.CompactTextString(m) }
+func (*GeofenceEventsRequest) ProtoMessage()    {}
+func (*GeofenceEventsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{25}
+}
+
+func (m *GeofenceEventsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GeofenceEventsRequest.Unmarshal(m, b)
+}
+func (m *GeofenceEventsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GeofenceEventsRequest.Marshal(b, m, deterministic)
+}
+func (m *GeofenceEventsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GeofenceEventsRequest.Merge(m, src)
+}
+func (m *GeofenceEventsRequest) XXX_Size() int {
+	return xxx_messageInfo_GeofenceEventsRequest.Size(m)
+}
+func (m *GeofenceEventsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GeofenceEventsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GeofenceEventsRequest proto.InternalMessageInfo
+
+func (m *GeofenceEventsRequest) GetFenceId() string {
 	if m != nil {
-		return m.Bllng
+		return m.FenceId
+	}
+	return ""
+}
+
+func (m *GeofenceEventsRequest) GetDeviceId() string {
+	if m != nil {
+		return m.DeviceId
+	}
+	return ""
+}
+
+func (m *GeofenceEventsRequest) GetStart() *timestamp.Timestamp {
+	if m != nil {
+		return m.Start
+	}
+	return nil
+}
+
+func (m *GeofenceEventsRequest) GetEnd() *timestamp.Timestamp {
+	if m != nil {
+		return m.End
+	}
+	return nil
+}
+
+func (m *GeofenceEventsRequest) GetCount() int32 {
+	if m != nil {
+		return m.Count
 	}
 	return 0
 }
 
+func (m *GeofenceEventsRequest) GetCursor() []byte {
+	if m != nil {
+		return m.Cursor
+	}
+	return nil
+}
+
+type GeofenceEventList struct {
+	Events []*GeofenceEvent `protobuf:"bytes,1,rep,name=events,proto3" json:"events,omitempty"`
+	// opaque token to query the next page, empty when there are no more results
+	NextCursor           []byte   `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GeofenceEventList) Reset()         { *m = GeofenceEventList{} }
+func (m *GeofenceEventList) String() string { return proto.CompactTextString(m) }
+func (*GeofenceEventList) ProtoMessage()    {}
+func (*GeofenceEventList) Descriptor() ([]byte, []int) {
+	return fileDescriptor_06af7e6094328dd5, []int{26}
+}
+
+func (m *GeofenceEventList) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GeofenceEventList.Unmarshal(m, b)
+}
+func (m *GeofenceEventList) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GeofenceEventList.Marshal(b, m, deterministic)
+}
+func (m *GeofenceEventList) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GeofenceEventList.Merge(m, src)
+}
+func (m *GeofenceEventList) XXX_Size() int {
+	return xxx_messageInfo_GeofenceEventList.Size(m)
+}
+func (m *GeofenceEventList) XXX_DiscardUnknown() {
+	xxx_messageInfo_GeofenceEventList.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GeofenceEventList proto.InternalMessageInfo
+
+func (m *GeofenceEventList) GetEvents() []*GeofenceEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *GeofenceEventList) GetNextCursor() []byte {
+	if m != nil {
+		return m.NextCursor
+	}
+	return nil
+}
+
 func init() {
+	proto.RegisterEnum("GeofenceEvent_Type", GeofenceEvent_Type_name, GeofenceEvent_Type_value)
 	proto.RegisterType((*DataPoint)(nil), "DataPoint")
+	proto.RegisterType((*Metadata)(nil), "Metadata")
+	proto.RegisterType((*GatewayMetadata)(nil), "GatewayMetadata")
 	proto.RegisterType((*KeyList)(nil), "KeyList")
+	proto.RegisterType((*StoreBatchRequest)(nil), "StoreBatchRequest")
+	proto.RegisterType((*StoreSummary)(nil), "StoreSummary")
+	proto.RegisterType((*StoreError)(nil), "StoreError")
 	proto.RegisterType((*DataPoints)(nil), "DataPoints")
 	proto.RegisterType((*GetRequest)(nil), "GetRequest")
+	proto.RegisterType((*DeleteRequest)(nil), "DeleteRequest")
+	proto.RegisterType((*GetAllRequest)(nil), "GetAllRequest")
 	proto.RegisterType((*RadiusSearchRequest)(nil), "RadiusSearchRequest")
 	proto.RegisterType((*RectSearchRequest)(nil), "RectSearchRequest")
+	proto.RegisterType((*NearestSearchRequest)(nil), "NearestSearchRequest")
+	proto.RegisterType((*WatchRequest)(nil), "WatchRequest")
+	proto.RegisterType((*LatLng)(nil), "LatLng")
+	proto.RegisterType((*Ring)(nil), "Ring")
+	proto.RegisterType((*Polygon)(nil), "Polygon")
+	proto.RegisterType((*PolygonSearchRequest)(nil), "PolygonSearchRequest")
+	proto.RegisterType((*RadiusHistorySearchRequest)(nil), "RadiusHistorySearchRequest")
+	proto.RegisterType((*RectHistorySearchRequest)(nil), "RectHistorySearchRequest")
+	proto.RegisterType((*Geofence)(nil), "Geofence")
+	proto.RegisterType((*Geofences)(nil), "Geofences")
+	proto.RegisterType((*GeofenceRequest)(nil), "GeofenceRequest")
+	proto.RegisterType((*GeofenceEvent)(nil), "GeofenceEvent")
+	proto.RegisterType((*GeofenceEventsRequest)(nil), "GeofenceEventsRequest")
+	proto.RegisterType((*GeofenceEventList)(nil), "GeofenceEventList")
+}
+
+func init() { proto.RegisterFile("geottnsvc.proto", fileDescriptor_06af7e6094328dd5) }
+
+var fileDescriptor_06af7e6094328dd5 = []byte{
+	// 1493 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xb4, 0x57, 0x4f, 0x73, 0x1b, 0xc5,
+	0x12, 0xd7, 0x6a, 0xff, 0x48, 0x6a, 0x59, 0x8e, 0x32, 0xb1, 0x53, 0x8a, 0xfc, 0xf2, 0x62, 0xef,
+	0xab, 0xb2, 0x55, 0xaf, 0xcc, 0x24, 0x28, 0xa4, 0xb8, 0x04, 0xaa, 0x02, 0x31, 0xc2, 0x95, 0x60,
+	0xc2, 0xd8, 0x54, 0x38, 0x50, 0xe5, 0x9a, 0xec, 0x8e, 0x37, 0x4b, 0xa4, 0x5d, 0xb1, 0x3b, 0x0a,
+	0xd6, 0x09, 0x0e, 0x9c, 0xb9, 0x53, 0xc5, 0x47, 0xe0, 0xd3, 0x70, 0xe2, 0xc2, 0x95, 0x4f, 0x41,
+	0x51, 0xd4, 0xfc, 0xd9, 0x5d, 0xad, 0x2c, 0x39, 0x16, 0x05, 0xb7, 0xed, 0x9e, 0x9e, 0xe9, 0xee,
+	0x5f, 0xf7, 0xf4, 0xfc, 0x16, 0xae, 0x05, 0x2c, 0xe6, 0x3c, 0x4a, 0x5f, 0x7b, 0x78, 0x9c, 0xc4,
+	0x3c, 0xee, 0x6e, 0x05, 0x71, 0x1c, 0x0c, 0xd9, 0x5d, 0x29, 0xbd, 0x98, 0x9c, 0xdd, 0x65, 0xa3,
+	0x31, 0x9f, 0xea, 0xc5, 0x3b, 0xf3, 0x8b, 0x3c, 0x1c, 0xb1, 0x94, 0xd3, 0xd1, 0x58, 0x19, 0xb8,
+	0x7f, 0x1a, 0xd0, 0x78, 0x4c, 0x39, 0x7d, 0x16, 0x87, 0x11, 0x47, 0x9b, 0xe0, 0xd0, 0xf1, 0xf8,
+	0x34, 0xf4, 0x3b, 0xc6, 0xb6, 0xd1, 0x6b, 0x10, 0x9b, 0x8e, 0xc7, 0x87, 0x3e, 0xda, 0x82, 0x86,
+	0xcf, 0x5e, 0x87, 0x1e, 0x13, 0x2b, 0x55, 0xb9, 0x52, 0x57, 0x8a, 0x43, 0x1f, 0x75, 0xa1, 0x3e,
+	0xa4, 0x3c, 0xe4, 0x13, 0x9f, 0x75, 0xcc, 0x6d, 0xa3, 0x67, 0x90, 0x5c, 0x46, 0xff, 0x81, 0xc6,
+	0x30, 0x8e, 0x02, 0xb5, 0x68, 0xc9, 0xc5, 0x42, 0x81, 0x30, 0x58, 0x22, 0x9c, 0x8e, 0xbd, 0x6d,
+	0xf4, 0x9a, 0xfd, 0x2e, 0x56, 0xb1, 0xe2, 0x2c, 0x56, 0x7c, 0x92, 0xc5, 0x4a, 0xa4, 0x1d, 0xea,
+	0x40, 0x6d, 0x4c, 0xa7, 0xc3, 0x98, 0xfa, 0x1d, 0x67, 0xdb, 0xe8, 0xad, 0x91, 0x4c, 0x14, 0x31,
+	0xf8, 0x61, 0xca, 0x69, 0xe4, 0xb1, 0x4e, 0x4d, 0xc5, 0x90, 0xc9, 0xe8, 0x36, 0x58, 0x23, 0xc6,
+	0x69, 0xa7, 0x2e, 0xbd, 0x34, 0xf0, 0x27, 0x8c, 0x53, 0x9f, 0x72, 0x4a, 0xa4, 0xda, 0xfd, 0xc3,
+	0x80, 0x7a, 0xa6, 0x42, 0x77, 0xa0, 0x29, 0x3c, 0x9d, 0xa6, 0xf1, 0x24, 0xf1, 0x98, 0x06, 0x01,
+	0x84, 0xea, 0x58, 0x6a, 0x10, 0x02, 0xeb, 0xcc, 0x8b, 0xb8, 0x04, 0xa1, 0x45, 0xe4, 0xb7, 0x44,
+	0x87, 0x72, 0x7a, 0x9a, 0x50, 0xae, 0x10, 0x10, 0xe8, 0x08, 0x07, 0x94, 0x4b, 0x04, 0xce, 0x12,
+	0xf6, 0xf5, 0x84, 0x45, 0xde, 0x54, 0x22, 0x50, 0x25, 0x85, 0x02, 0xed, 0x43, 0x3d, 0xa0, 0x9c,
+	0x7d, 0x43, 0xa7, 0x69, 0xc7, 0xde, 0x36, 0x7b, 0xcd, 0x7e, 0x1b, 0x0f, 0x94, 0x22, 0x0f, 0x33,
+	0xb7, 0x10, 0x67, 0xb1, 0x94, 0x87, 0x23, 0xca, 0x99, 0x42, 0xa0, 0x4e, 0x0a, 0x85, 0xc0, 0x80,
+	0x7a, 0xde, 0x24, 0xa1, 0xde, 0x34, 0xc3, 0x20, 0x93, 0x05, 0x72, 0x3e, 0xf3, 0x62, 0x9f, 0x25,
+	0x12, 0x86, 0x06, 0xc9, 0x44, 0xf7, 0x67, 0x03, 0xae, 0xcd, 0x79, 0x44, 0xb7, 0x01, 0xb4, 0xcf,
+	0xa2, 0x13, 0x1a, 0x5a, 0x73, 0xe8, 0x0b, 0x0c, 0x92, 0x34, 0x0d, 0x25, 0x06, 0x55, 0x22, 0xbf,
+	0x51, 0x1b, 0xcc, 0x34, 0x4a, 0x64, 0xf6, 0x55, 0x22, 0x3e, 0x4b, 0x6d, 0x61, 0x5d, 0xd6, 0x16,
+	0xf6, 0x7c, 0x5b, 0x88, 0x44, 0x86, 0x7a, 0xa7, 0xc8, 0xd2, 0x26, 0xb9, 0xec, 0xde, 0x86, 0xda,
+	0x13, 0x36, 0x7d, 0x1a, 0xa6, 0x5c, 0x84, 0xf1, 0x8a, 0x4d, 0xd3, 0x8e, 0xb1, 0x6d, 0xf6, 0x1a,
+	0x44, 0x7e, 0xbb, 0xef, 0xc2, 0xf5, 0x63, 0x1e, 0x27, 0xec, 0x03, 0xca, 0xbd, 0x97, 0x44, 0xa0,
+	0x9c, 0x72, 0xe4, 0x82, 0x33, 0x16, 0xdd, 0xad, 0x4c, 0x9b, 0x7d, 0xc0, 0x79, 0xc3, 0x13, 0xbd,
+	0xe2, 0x06, 0xb0, 0x26, 0x37, 0x1e, 0x4f, 0x46, 0x23, 0x9a, 0x4c, 0xd1, 0x4d, 0x70, 0x52, 0x21,
+	0xab, 0xf4, 0x4d, 0xa2, 0x25, 0x11, 0x5b, 0xc2, 0xbe, 0x62, 0x9e, 0xa8, 0x40, 0x55, 0xae, 0xe4,
+	0x32, 0xfa, 0x1f, 0x38, 0x2c, 0x49, 0xe2, 0x24, 0xed, 0x98, 0xd2, 0x4f, 0x13, 0xcb, 0x23, 0x0f,
+	0x84, 0x8e, 0xe8, 0x25, 0xf7, 0x21, 0x40, 0xa1, 0x45, 0x1b, 0x60, 0x87, 0x91, 0xcf, 0xce, 0xb5,
+	0x17, 0x25, 0x88, 0x6a, 0x8d, 0x58, 0x9a, 0xd2, 0x80, 0xe9, 0xcb, 0x96, 0x89, 0xee, 0x67, 0x00,
+	0x79, 0xec, 0xe9, 0x55, 0x12, 0x13, 0x1d, 0x1d, 0xb1, 0x73, 0x7e, 0xea, 0x4d, 0x92, 0x34, 0x4e,
+	0xe4, 0x79, 0x6b, 0x04, 0x84, 0xea, 0x43, 0xa9, 0x71, 0xff, 0x0b, 0x30, 0x60, 0x3c, 0xc3, 0xaa,
+	0x0d, 0xe6, 0x2b, 0x36, 0xd5, 0x35, 0x17, 0x9f, 0xee, 0x0e, 0xb4, 0x1e, 0xb3, 0x21, 0xe3, 0x6c,
+	0xb9, 0xc9, 0x2f, 0x06, 0xb4, 0x06, 0x8c, 0x3f, 0x1a, 0x0e, 0x97, 0xda, 0xa0, 0x7b, 0x60, 0xa7,
+	0x9c, 0x26, 0xea, 0xe6, 0x5c, 0x7e, 0xd9, 0x95, 0x21, 0xda, 0x07, 0x93, 0x45, 0x7e, 0xc7, 0x7c,
+	0xa3, 0xbd, 0x30, 0x13, 0x48, 0x7a, 0xf1, 0x24, 0xe2, 0xb2, 0xd7, 0x6c, 0xa2, 0x04, 0x51, 0x46,
+	0x9d, 0xb8, 0x2d, 0x13, 0xd7, 0x12, 0xda, 0x81, 0xb5, 0x78, 0xe8, 0xb3, 0x94, 0x9f, 0x9e, 0x85,
+	0x49, 0xca, 0xf5, 0x65, 0x6a, 0x2a, 0xdd, 0x47, 0x42, 0xe5, 0x7e, 0x0b, 0x37, 0x08, 0xf5, 0xc3,
+	0x49, 0x7a, 0xcc, 0x68, 0x52, 0x34, 0x53, 0x1b, 0xcc, 0x21, 0xe5, 0x32, 0x33, 0x83, 0x88, 0x4f,
+	0xa9, 0x89, 0x82, 0x4e, 0x55, 0x6b, 0xa2, 0x40, 0x78, 0x4d, 0xe4, 0x56, 0x3d, 0x0f, 0xb5, 0xb4,
+	0x5a, 0x8c, 0xee, 0x8f, 0x06, 0x5c, 0x27, 0xcc, 0xe3, 0x65, 0xff, 0x1b, 0x60, 0x4f, 0x92, 0x22,
+	0x02, 0x25, 0x68, 0x6d, 0x1e, 0x85, 0x12, 0x84, 0xf6, 0xc5, 0x50, 0xd8, 0xaa, 0x30, 0x94, 0xa0,
+	0xb5, 0x51, 0xa0, 0x6f, 0xa5, 0x12, 0x8a, 0xd8, 0xec, 0xc5, 0xb1, 0x39, 0xa5, 0xd8, 0x52, 0xd8,
+	0x38, 0x62, 0x34, 0x61, 0x29, 0x5f, 0x1d, 0x9d, 0xdc, 0x93, 0x39, 0xeb, 0x69, 0x07, 0xd6, 0x46,
+	0xf4, 0xfc, 0x34, 0x9f, 0xe2, 0x2a, 0xb8, 0xe6, 0x88, 0x9e, 0x3f, 0xd6, 0x2a, 0xf7, 0x27, 0x03,
+	0xd6, 0x9e, 0xcf, 0x5e, 0xec, 0x05, 0x13, 0x00, 0xed, 0xe7, 0xd8, 0xab, 0x46, 0xdb, 0xc0, 0x0b,
+	0xaa, 0x98, 0x57, 0x64, 0x17, 0xac, 0x84, 0x79, 0x5c, 0x37, 0x19, 0xc2, 0x17, 0xf0, 0x26, 0x72,
+	0x1d, 0xb9, 0x50, 0x1b, 0xc7, 0xc3, 0x69, 0x10, 0x47, 0x32, 0xb0, 0x66, 0xbf, 0x8e, 0x9f, 0x29,
+	0x99, 0x64, 0x0b, 0xee, 0x3e, 0x38, 0x4f, 0x29, 0x7f, 0x1a, 0x05, 0x57, 0x41, 0xc1, 0xdd, 0x03,
+	0x8b, 0x84, 0x51, 0x80, 0xee, 0xcc, 0xdd, 0xe1, 0x1a, 0x56, 0x87, 0xe4, 0x93, 0x69, 0x17, 0x6a,
+	0xda, 0x15, 0xda, 0x02, 0x3b, 0x09, 0xa3, 0x20, 0x33, 0xb5, 0xb1, 0x38, 0x81, 0x28, 0x9d, 0xfb,
+	0x12, 0x36, 0xb4, 0x5d, 0xb9, 0x24, 0x33, 0xa1, 0x1b, 0x4b, 0x42, 0x2f, 0x4a, 0x52, 0x5d, 0x5c,
+	0x7c, 0xb3, 0x54, 0xfc, 0xdf, 0x0d, 0xe8, 0x2a, 0x50, 0x3f, 0x0e, 0xc5, 0x58, 0x9c, 0xfe, 0x73,
+	0x37, 0x24, 0x9f, 0x12, 0xd6, 0x8a, 0x53, 0xc2, 0x5e, 0x71, 0x4a, 0x38, 0x8b, 0x13, 0xad, 0x95,
+	0x12, 0xfd, 0xae, 0x0a, 0x1d, 0xd1, 0x11, 0x0b, 0xd3, 0xfc, 0xb7, 0x2e, 0x62, 0x0e, 0x81, 0xbd,
+	0x22, 0x04, 0xce, 0x8a, 0x10, 0xd4, 0x16, 0x43, 0x50, 0x2f, 0x41, 0xf0, 0x83, 0x01, 0xf5, 0x01,
+	0x8b, 0xcf, 0x98, 0x60, 0x52, 0xeb, 0x50, 0xcd, 0xf9, 0x40, 0x35, 0x94, 0x44, 0x20, 0xa2, 0xa3,
+	0xec, 0x91, 0x92, 0xdf, 0x59, 0xf5, 0xcd, 0x0b, 0xd5, 0xb7, 0x16, 0x55, 0xdf, 0x2e, 0x55, 0x7f,
+	0xa6, 0x55, 0x9d, 0x65, 0xb7, 0xec, 0x1d, 0x68, 0x64, 0xf1, 0xa4, 0x68, 0x0f, 0x1a, 0x41, 0x26,
+	0xe8, 0x4b, 0xd1, 0xc0, 0xd9, 0x32, 0x29, 0xd6, 0xdc, 0x1d, 0xb8, 0x96, 0xab, 0x75, 0xfd, 0xe6,
+	0x92, 0x71, 0xbf, 0xaf, 0x42, 0x2b, 0xb3, 0x39, 0x78, 0xcd, 0x22, 0x8e, 0x6e, 0x41, 0x5d, 0x4a,
+	0x05, 0x09, 0xaa, 0x49, 0xf9, 0x4d, 0x84, 0x78, 0x0f, 0x2c, 0x3e, 0x1d, 0x2b, 0x2a, 0xb8, 0xde,
+	0xbf, 0x81, 0x4b, 0xa7, 0xe2, 0x93, 0xe9, 0x98, 0x11, 0x69, 0x90, 0xf3, 0x5f, 0xeb, 0x8a, 0xfc,
+	0x77, 0x96, 0x52, 0xd9, 0x97, 0x51, 0x2a, 0x67, 0x8e, 0x52, 0xb9, 0x3d, 0xb0, 0x84, 0x5f, 0xd4,
+	0x84, 0xda, 0xe7, 0x47, 0x4f, 0x8e, 0x3e, 0x7d, 0x7e, 0xd4, 0xae, 0xa0, 0x06, 0xd8, 0x07, 0x47,
+	0x27, 0x07, 0xa4, 0x6d, 0xa0, 0x3a, 0x58, 0x07, 0x5f, 0x1c, 0x9e, 0xb4, 0xab, 0xee, 0x6f, 0x06,
+	0x6c, 0x96, 0x02, 0x4e, 0x33, 0xc0, 0xfe, 0x2e, 0x1c, 0x79, 0x43, 0x9b, 0x2b, 0x36, 0xb4, 0xb5,
+	0x62, 0x43, 0x5f, 0xe9, 0xe5, 0xfa, 0x12, 0xae, 0x97, 0xd2, 0x93, 0x54, 0x72, 0x17, 0x1c, 0x26,
+	0x73, 0xd5, 0x4d, 0xb4, 0x5e, 0xae, 0x19, 0xd1, 0xab, 0x6f, 0x24, 0x53, 0xfd, 0x5f, 0x6b, 0xe0,
+	0x0c, 0x58, 0x7c, 0x72, 0x72, 0x84, 0xde, 0x02, 0x5b, 0x12, 0x3d, 0x34, 0xc3, 0xca, 0xba, 0x37,
+	0x2f, 0x24, 0x73, 0x20, 0x7e, 0xd6, 0xdc, 0x0a, 0x7a, 0x1b, 0xa0, 0x60, 0xae, 0x08, 0xe1, 0x0b,
+	0x34, 0xb6, 0xdb, 0xc2, 0xb3, 0x0c, 0xd5, 0xad, 0xa0, 0x7d, 0x68, 0x2a, 0x0d, 0x4f, 0x18, 0x1d,
+	0x95, 0xfc, 0xcc, 0xdb, 0xf6, 0x0c, 0x74, 0x1f, 0xd6, 0x66, 0x5f, 0x42, 0xb4, 0xf0, 0x61, 0xec,
+	0x36, 0x8b, 0x43, 0x52, 0xb7, 0x82, 0xee, 0x02, 0x14, 0x4f, 0x22, 0x5a, 0xf0, 0x3e, 0xce, 0x6f,
+	0x78, 0x00, 0xad, 0xd2, 0x2b, 0x84, 0x36, 0xf1, 0xa2, 0x57, 0x69, 0xc1, 0xb6, 0x12, 0x9f, 0x40,
+	0x9b, 0x78, 0x11, 0xbf, 0x98, 0xdf, 0xf6, 0x28, 0xe3, 0x68, 0xa5, 0x09, 0x8d, 0xb6, 0xf0, 0xf2,
+	0xe7, 0x69, 0xfe, 0x88, 0xf7, 0x14, 0xc9, 0x2a, 0x1f, 0x70, 0x0b, 0x2f, 0x1b, 0xfb, 0xf3, 0xdb,
+	0xb7, 0xc1, 0x1c, 0x30, 0x8e, 0x9a, 0xb8, 0xe0, 0xd0, 0xdd, 0x99, 0x42, 0xb8, 0x15, 0xb4, 0x07,
+	0x8e, 0xe2, 0xc6, 0x68, 0x1d, 0xab, 0x8f, 0x25, 0x47, 0xfd, 0x1f, 0xac, 0x27, 0x82, 0xc1, 0x2c,
+	0xe9, 0x91, 0x6e, 0x1d, 0xeb, 0x3f, 0x1f, 0xb7, 0x82, 0x76, 0xc1, 0x96, 0x4c, 0x08, 0xb5, 0xf0,
+	0x2c, 0x23, 0x2a, 0xbb, 0xbe, 0x67, 0xa0, 0x3e, 0x38, 0x8a, 0xbc, 0xa3, 0x75, 0x5c, 0x62, 0xf1,
+	0x97, 0x74, 0x62, 0x1f, 0x9a, 0xcf, 0x26, 0x3c, 0x1f, 0xfa, 0xc5, 0x40, 0xbd, 0x64, 0xcf, 0x3e,
+	0x34, 0x07, 0xac, 0xd8, 0xd3, 0xc6, 0x73, 0xd3, 0xb6, 0x5b, 0x9c, 0xe2, 0x56, 0xd0, 0x43, 0x58,
+	0x57, 0xc1, 0x5c, 0xb2, 0x61, 0xb9, 0xaf, 0x07, 0xd0, 0x12, 0x28, 0x14, 0xaf, 0xc0, 0x32, 0xc0,
+	0x20, 0x3f, 0x54, 0xc0, 0xfb, 0x3e, 0xac, 0x97, 0xe7, 0x1a, 0xba, 0x89, 0x17, 0x0e, 0xba, 0x2e,
+	0xc2, 0x17, 0x26, 0x84, 0x5b, 0x79, 0xe1, 0xc8, 0xd3, 0xef, 0xff, 0x35, 0x00, 0x47, 0x6c, 0x94,
+	0xdc, 0x80, 0x11, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -351,74 +1892,252 @@ var _ grpc.ClientConn
 // is compatible with the grpc package it is being compiled against.
 const _ = grpc.SupportPackageIsVersion4
 
-// GeoTTNClient is the client API for GeoTTN service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type GeoTTNClient interface {
-	Store(ctx context.Context, in *DataPoint, opts ...grpc.CallOption) (*empty.Empty, error)
-	RadiusSearch(ctx context.Context, in *RadiusSearchRequest, opts ...grpc.CallOption) (*DataPoints, error)
-	RectSearch(ctx context.Context, in *RectSearchRequest, opts ...grpc.CallOption) (*DataPoints, error)
-	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*DataPoint, error)
-	GetAll(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*DataPoints, error)
-	Keys(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*KeyList, error)
+// GeoTTNClient is the client API for GeoTTN service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type GeoTTNClient interface {
+	Store(ctx context.Context, in *DataPoint, opts ...grpc.CallOption) (*empty.Empty, error)
+	StoreBatch(ctx context.Context, in *StoreBatchRequest, opts ...grpc.CallOption) (*StoreSummary, error)
+	StoreStream(ctx context.Context, opts ...grpc.CallOption) (GeoTTN_StoreStreamClient, error)
+	RadiusSearch(ctx context.Context, in *RadiusSearchRequest, opts ...grpc.CallOption) (*DataPoints, error)
+	RectSearch(ctx context.Context, in *RectSearchRequest, opts ...grpc.CallOption) (*DataPoints, error)
+	PolygonSearch(ctx context.Context, in *PolygonSearchRequest, opts ...grpc.CallOption) (*DataPoints, error)
+	NearestSearch(ctx context.Context, in *NearestSearchRequest, opts ...grpc.CallOption) (*DataPoints, error)
+	RadiusHistorySearch(ctx context.Context, in *RadiusHistorySearchRequest, opts ...grpc.CallOption) (*DataPoints, error)
+	RectHistorySearch(ctx context.Context, in *RectHistorySearchRequest, opts ...grpc.CallOption) (*DataPoints, error)
+	Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*DataPoint, error)
+	GetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (*DataPoints, error)
+	Keys(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*KeyList, error)
+	Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (GeoTTN_WatchClient, error)
+	Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	PutGeofence(ctx context.Context, in *Geofence, opts ...grpc.CallOption) (*empty.Empty, error)
+	GetGeofence(ctx context.Context, in *GeofenceRequest, opts ...grpc.CallOption) (*Geofence, error)
+	DeleteGeofence(ctx context.Context, in *GeofenceRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	ListGeofences(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Geofences, error)
+	GeofenceEvents(ctx context.Context, in *GeofenceEventsRequest, opts ...grpc.CallOption) (*GeofenceEventList, error)
+}
+
+type geoTTNClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewGeoTTNClient(cc *grpc.ClientConn) GeoTTNClient {
+	return &geoTTNClient{cc}
+}
+
+func (c *geoTTNClient) Store(ctx context.Context, in *DataPoint, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/GeoTTN/Store", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) StoreBatch(ctx context.Context, in *StoreBatchRequest, opts ...grpc.CallOption) (*StoreSummary, error) {
+	out := new(StoreSummary)
+	err := c.cc.Invoke(ctx, "/GeoTTN/StoreBatch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) StoreStream(ctx context.Context, opts ...grpc.CallOption) (GeoTTN_StoreStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GeoTTN_serviceDesc.Streams[0], "/GeoTTN/StoreStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &geoTTNStoreStreamClient{stream}
+	return x, nil
+}
+
+type GeoTTN_StoreStreamClient interface {
+	Send(*DataPoint) error
+	CloseAndRecv() (*StoreSummary, error)
+	grpc.ClientStream
+}
+
+type geoTTNStoreStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *geoTTNStoreStreamClient) Send(m *DataPoint) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *geoTTNStoreStreamClient) CloseAndRecv() (*StoreSummary, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(StoreSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *geoTTNClient) RadiusSearch(ctx context.Context, in *RadiusSearchRequest, opts ...grpc.CallOption) (*DataPoints, error) {
+	out := new(DataPoints)
+	err := c.cc.Invoke(ctx, "/GeoTTN/RadiusSearch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) RectSearch(ctx context.Context, in *RectSearchRequest, opts ...grpc.CallOption) (*DataPoints, error) {
+	out := new(DataPoints)
+	err := c.cc.Invoke(ctx, "/GeoTTN/RectSearch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) PolygonSearch(ctx context.Context, in *PolygonSearchRequest, opts ...grpc.CallOption) (*DataPoints, error) {
+	out := new(DataPoints)
+	err := c.cc.Invoke(ctx, "/GeoTTN/PolygonSearch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) NearestSearch(ctx context.Context, in *NearestSearchRequest, opts ...grpc.CallOption) (*DataPoints, error) {
+	out := new(DataPoints)
+	err := c.cc.Invoke(ctx, "/GeoTTN/NearestSearch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) RadiusHistorySearch(ctx context.Context, in *RadiusHistorySearchRequest, opts ...grpc.CallOption) (*DataPoints, error) {
+	out := new(DataPoints)
+	err := c.cc.Invoke(ctx, "/GeoTTN/RadiusHistorySearch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) RectHistorySearch(ctx context.Context, in *RectHistorySearchRequest, opts ...grpc.CallOption) (*DataPoints, error) {
+	out := new(DataPoints)
+	err := c.cc.Invoke(ctx, "/GeoTTN/RectHistorySearch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*DataPoint, error) {
+	out := new(DataPoint)
+	err := c.cc.Invoke(ctx, "/GeoTTN/Get", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) GetAll(ctx context.Context, in *GetAllRequest, opts ...grpc.CallOption) (*DataPoints, error) {
+	out := new(DataPoints)
+	err := c.cc.Invoke(ctx, "/GeoTTN/GetAll", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) Keys(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*KeyList, error) {
+	out := new(KeyList)
+	err := c.cc.Invoke(ctx, "/GeoTTN/Keys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *geoTTNClient) Watch(ctx context.Context, in *WatchRequest, opts ...grpc.CallOption) (GeoTTN_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_GeoTTN_serviceDesc.Streams[1], "/GeoTTN/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &geoTTNWatchClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type GeoTTN_WatchClient interface {
+	Recv() (*DataPoint, error)
+	grpc.ClientStream
 }
 
-type geoTTNClient struct {
-	cc *grpc.ClientConn
+type geoTTNWatchClient struct {
+	grpc.ClientStream
 }
 
-func NewGeoTTNClient(cc *grpc.ClientConn) GeoTTNClient {
-	return &geoTTNClient{cc}
+func (x *geoTTNWatchClient) Recv() (*DataPoint, error) {
+	m := new(DataPoint)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
 }
 
-func (c *geoTTNClient) Store(ctx context.Context, in *DataPoint, opts ...grpc.CallOption) (*empty.Empty, error) {
+func (c *geoTTNClient) Delete(ctx context.Context, in *DeleteRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
 	out := new(empty.Empty)
-	err := c.cc.Invoke(ctx, "/GeoTTN/Store", in, out, opts...)
+	err := c.cc.Invoke(ctx, "/GeoTTN/Delete", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *geoTTNClient) RadiusSearch(ctx context.Context, in *RadiusSearchRequest, opts ...grpc.CallOption) (*DataPoints, error) {
-	out := new(DataPoints)
-	err := c.cc.Invoke(ctx, "/GeoTTN/RadiusSearch", in, out, opts...)
+func (c *geoTTNClient) PutGeofence(ctx context.Context, in *Geofence, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/GeoTTN/PutGeofence", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *geoTTNClient) RectSearch(ctx context.Context, in *RectSearchRequest, opts ...grpc.CallOption) (*DataPoints, error) {
-	out := new(DataPoints)
-	err := c.cc.Invoke(ctx, "/GeoTTN/RectSearch", in, out, opts...)
+func (c *geoTTNClient) GetGeofence(ctx context.Context, in *GeofenceRequest, opts ...grpc.CallOption) (*Geofence, error) {
+	out := new(Geofence)
+	err := c.cc.Invoke(ctx, "/GeoTTN/GetGeofence", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *geoTTNClient) Get(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*DataPoint, error) {
-	out := new(DataPoint)
-	err := c.cc.Invoke(ctx, "/GeoTTN/Get", in, out, opts...)
+func (c *geoTTNClient) DeleteGeofence(ctx context.Context, in *GeofenceRequest, opts ...grpc.CallOption) (*empty.Empty, error) {
+	out := new(empty.Empty)
+	err := c.cc.Invoke(ctx, "/GeoTTN/DeleteGeofence", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *geoTTNClient) GetAll(ctx context.Context, in *GetRequest, opts ...grpc.CallOption) (*DataPoints, error) {
-	out := new(DataPoints)
-	err := c.cc.Invoke(ctx, "/GeoTTN/GetAll", in, out, opts...)
+func (c *geoTTNClient) ListGeofences(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*Geofences, error) {
+	out := new(Geofences)
+	err := c.cc.Invoke(ctx, "/GeoTTN/ListGeofences", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return out, nil
 }
 
-func (c *geoTTNClient) Keys(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*KeyList, error) {
-	out := new(KeyList)
-	err := c.cc.Invoke(ctx, "/GeoTTN/Keys", in, out, opts...)
+func (c *geoTTNClient) GeofenceEvents(ctx context.Context, in *GeofenceEventsRequest, opts ...grpc.CallOption) (*GeofenceEventList, error) {
+	out := new(GeofenceEventList)
+	err := c.cc.Invoke(ctx, "/GeoTTN/GeofenceEvents", in, out, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -428,11 +2147,86 @@ func (c *geoTTNClient) Keys(ctx context.Context, in *empty.Empty, opts ...grpc.C
 // GeoTTNServer is the server API for GeoTTN service.
 type GeoTTNServer interface {
 	Store(context.Context, *DataPoint) (*empty.Empty, error)
+	StoreBatch(context.Context, *StoreBatchRequest) (*StoreSummary, error)
+	StoreStream(GeoTTN_StoreStreamServer) error
 	RadiusSearch(context.Context, *RadiusSearchRequest) (*DataPoints, error)
 	RectSearch(context.Context, *RectSearchRequest) (*DataPoints, error)
+	PolygonSearch(context.Context, *PolygonSearchRequest) (*DataPoints, error)
+	NearestSearch(context.Context, *NearestSearchRequest) (*DataPoints, error)
+	RadiusHistorySearch(context.Context, *RadiusHistorySearchRequest) (*DataPoints, error)
+	RectHistorySearch(context.Context, *RectHistorySearchRequest) (*DataPoints, error)
 	Get(context.Context, *GetRequest) (*DataPoint, error)
-	GetAll(context.Context, *GetRequest) (*DataPoints, error)
+	GetAll(context.Context, *GetAllRequest) (*DataPoints, error)
 	Keys(context.Context, *empty.Empty) (*KeyList, error)
+	Watch(*WatchRequest, GeoTTN_WatchServer) error
+	Delete(context.Context, *DeleteRequest) (*empty.Empty, error)
+	PutGeofence(context.Context, *Geofence) (*empty.Empty, error)
+	GetGeofence(context.Context, *GeofenceRequest) (*Geofence, error)
+	DeleteGeofence(context.Context, *GeofenceRequest) (*empty.Empty, error)
+	ListGeofences(context.Context, *empty.Empty) (*Geofences, error)
+	GeofenceEvents(context.Context, *GeofenceEventsRequest) (*GeofenceEventList, error)
+}
+
+// UnimplementedGeoTTNServer can be embedded to have forward compatible implementations.
+type UnimplementedGeoTTNServer struct {
+}
+
+func (*UnimplementedGeoTTNServer) Store(ctx context.Context, req *DataPoint) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Store not implemented")
+}
+func (*UnimplementedGeoTTNServer) StoreBatch(ctx context.Context, req *StoreBatchRequest) (*StoreSummary, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StoreBatch not implemented")
+}
+func (*UnimplementedGeoTTNServer) StoreStream(srv GeoTTN_StoreStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method StoreStream not implemented")
+}
+func (*UnimplementedGeoTTNServer) RadiusSearch(ctx context.Context, req *RadiusSearchRequest) (*DataPoints, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RadiusSearch not implemented")
+}
+func (*UnimplementedGeoTTNServer) RectSearch(ctx context.Context, req *RectSearchRequest) (*DataPoints, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RectSearch not implemented")
+}
+func (*UnimplementedGeoTTNServer) PolygonSearch(ctx context.Context, req *PolygonSearchRequest) (*DataPoints, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PolygonSearch not implemented")
+}
+func (*UnimplementedGeoTTNServer) NearestSearch(ctx context.Context, req *NearestSearchRequest) (*DataPoints, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NearestSearch not implemented")
+}
+func (*UnimplementedGeoTTNServer) RadiusHistorySearch(ctx context.Context, req *RadiusHistorySearchRequest) (*DataPoints, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RadiusHistorySearch not implemented")
+}
+func (*UnimplementedGeoTTNServer) RectHistorySearch(ctx context.Context, req *RectHistorySearchRequest) (*DataPoints, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RectHistorySearch not implemented")
+}
+func (*UnimplementedGeoTTNServer) Get(ctx context.Context, req *GetRequest) (*DataPoint, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (*UnimplementedGeoTTNServer) GetAll(ctx context.Context, req *GetAllRequest) (*DataPoints, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAll not implemented")
+}
+func (*UnimplementedGeoTTNServer) Keys(ctx context.Context, req *empty.Empty) (*KeyList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Keys not implemented")
+}
+func (*UnimplementedGeoTTNServer) Watch(req *WatchRequest, srv GeoTTN_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
+}
+func (*UnimplementedGeoTTNServer) Delete(ctx context.Context, req *DeleteRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (*UnimplementedGeoTTNServer) PutGeofence(ctx context.Context, req *Geofence) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PutGeofence not implemented")
+}
+func (*UnimplementedGeoTTNServer) GetGeofence(ctx context.Context, req *GeofenceRequest) (*Geofence, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGeofence not implemented")
+}
+func (*UnimplementedGeoTTNServer) DeleteGeofence(ctx context.Context, req *GeofenceRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteGeofence not implemented")
+}
+func (*UnimplementedGeoTTNServer) ListGeofences(ctx context.Context, req *empty.Empty) (*Geofences, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGeofences not implemented")
+}
+func (*UnimplementedGeoTTNServer) GeofenceEvents(ctx context.Context, req *GeofenceEventsRequest) (*GeofenceEventList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GeofenceEvents not implemented")
 }
 
 func RegisterGeoTTNServer(s *grpc.Server, srv GeoTTNServer) {
@@ -457,6 +2251,50 @@ func _GeoTTN_Store_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GeoTTN_StoreBatch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StoreBatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).StoreBatch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/StoreBatch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).StoreBatch(ctx, req.(*StoreBatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoTTN_StoreStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(GeoTTNServer).StoreStream(&geoTTNStoreStreamServer{stream})
+}
+
+type GeoTTN_StoreStreamServer interface {
+	SendAndClose(*StoreSummary) error
+	Recv() (*DataPoint, error)
+	grpc.ServerStream
+}
+
+type geoTTNStoreStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *geoTTNStoreStreamServer) SendAndClose(m *StoreSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *geoTTNStoreStreamServer) Recv() (*DataPoint, error) {
+	m := new(DataPoint)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func _GeoTTN_RadiusSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(RadiusSearchRequest)
 	if err := dec(in); err != nil {
@@ -493,6 +2331,78 @@ func _GeoTTN_RectSearch_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GeoTTN_PolygonSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PolygonSearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).PolygonSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/PolygonSearch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).PolygonSearch(ctx, req.(*PolygonSearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoTTN_NearestSearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NearestSearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).NearestSearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/NearestSearch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).NearestSearch(ctx, req.(*NearestSearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoTTN_RadiusHistorySearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RadiusHistorySearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).RadiusHistorySearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/RadiusHistorySearch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).RadiusHistorySearch(ctx, req.(*RadiusHistorySearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoTTN_RectHistorySearch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RectHistorySearchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).RectHistorySearch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/RectHistorySearch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).RectHistorySearch(ctx, req.(*RectHistorySearchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _GeoTTN_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(GetRequest)
 	if err := dec(in); err != nil {
@@ -512,7 +2422,7 @@ func _GeoTTN_Get_Handler(srv interface{}, ctx context.Context, dec func(interfac
 }
 
 func _GeoTTN_GetAll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetRequest)
+	in := new(GetAllRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -524,7 +2434,7 @@ func _GeoTTN_GetAll_Handler(srv interface{}, ctx context.Context, dec func(inter
 		FullMethod: "/GeoTTN/GetAll",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(GeoTTNServer).GetAll(ctx, req.(*GetRequest))
+		return srv.(GeoTTNServer).GetAll(ctx, req.(*GetAllRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -547,6 +2457,135 @@ func _GeoTTN_Keys_Handler(srv interface{}, ctx context.Context, dec func(interfa
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GeoTTN_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GeoTTNServer).Watch(m, &geoTTNWatchServer{stream})
+}
+
+type GeoTTN_WatchServer interface {
+	Send(*DataPoint) error
+	grpc.ServerStream
+}
+
+type geoTTNWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *geoTTNWatchServer) Send(m *DataPoint) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _GeoTTN_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/Delete",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).Delete(ctx, req.(*DeleteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoTTN_PutGeofence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Geofence)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).PutGeofence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/PutGeofence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).PutGeofence(ctx, req.(*Geofence))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoTTN_GetGeofence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GeofenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).GetGeofence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/GetGeofence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).GetGeofence(ctx, req.(*GeofenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoTTN_DeleteGeofence_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GeofenceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).DeleteGeofence(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/DeleteGeofence",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).DeleteGeofence(ctx, req.(*GeofenceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoTTN_ListGeofences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).ListGeofences(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/ListGeofences",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).ListGeofences(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GeoTTN_GeofenceEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GeofenceEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GeoTTNServer).GeofenceEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GeoTTN/GeofenceEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GeoTTNServer).GeofenceEvents(ctx, req.(*GeofenceEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _GeoTTN_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "GeoTTN",
 	HandlerType: (*GeoTTNServer)(nil),
@@ -555,6 +2594,10 @@ var _GeoTTN_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Store",
 			Handler:    _GeoTTN_Store_Handler,
 		},
+		{
+			MethodName: "StoreBatch",
+			Handler:    _GeoTTN_StoreBatch_Handler,
+		},
 		{
 			MethodName: "RadiusSearch",
 			Handler:    _GeoTTN_RadiusSearch_Handler,
@@ -563,6 +2606,22 @@ var _GeoTTN_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RectSearch",
 			Handler:    _GeoTTN_RectSearch_Handler,
 		},
+		{
+			MethodName: "PolygonSearch",
+			Handler:    _GeoTTN_PolygonSearch_Handler,
+		},
+		{
+			MethodName: "NearestSearch",
+			Handler:    _GeoTTN_NearestSearch_Handler,
+		},
+		{
+			MethodName: "RadiusHistorySearch",
+			Handler:    _GeoTTN_RadiusHistorySearch_Handler,
+		},
+		{
+			MethodName: "RectHistorySearch",
+			Handler:    _GeoTTN_RectHistorySearch_Handler,
+		},
 		{
 			MethodName: "Get",
 			Handler:    _GeoTTN_Get_Handler,
@@ -575,42 +2634,42 @@ var _GeoTTN_serviceDesc = grpc.ServiceDesc{
 			MethodName: "Keys",
 			Handler:    _GeoTTN_Keys_Handler,
 		},
+		{
+			MethodName: "Delete",
+			Handler:    _GeoTTN_Delete_Handler,
+		},
+		{
+			MethodName: "PutGeofence",
+			Handler:    _GeoTTN_PutGeofence_Handler,
+		},
+		{
+			MethodName: "GetGeofence",
+			Handler:    _GeoTTN_GetGeofence_Handler,
+		},
+		{
+			MethodName: "DeleteGeofence",
+			Handler:    _GeoTTN_DeleteGeofence_Handler,
+		},
+		{
+			MethodName: "ListGeofences",
+			Handler:    _GeoTTN_ListGeofences_Handler,
+		},
+		{
+			MethodName: "GeofenceEvents",
+			Handler:    _GeoTTN_GeofenceEvents_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StoreStream",
+			Handler:       _GeoTTN_StoreStream_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Watch",
+			Handler:       _GeoTTN_Watch_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "geottnsvc.proto",
 }
-
-func init() { proto.RegisterFile("geottnsvc.proto", fileDescriptor_geottnsvc_d04c6f621b8ce5f8) }
-
-var fileDescriptor_geottnsvc_d04c6f621b8ce5f8 = []byte{
-	// 455 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x52, 0xdb, 0x6e, 0xd3, 0x40,
-	0x10, 0x8d, 0x9b, 0xc4, 0x8d, 0x27, 0x95, 0x80, 0xa1, 0x54, 0x96, 0xcb, 0xc5, 0x5a, 0xf1, 0x60,
-	0x21, 0xb1, 0x41, 0xe9, 0x17, 0x20, 0x81, 0xa2, 0xaa, 0x08, 0xc1, 0x36, 0xef, 0x68, 0x13, 0x0f,
-	0xc6, 0x8a, 0xe3, 0x35, 0xf6, 0xba, 0x92, 0x3f, 0x83, 0x6f, 0xe2, 0xc7, 0x90, 0xd7, 0x9b, 0x38,
-	0x4d, 0xe9, 0xdb, 0x9c, 0x8b, 0x47, 0x73, 0x8e, 0x17, 0x9e, 0x24, 0xa4, 0xb4, 0xce, 0xab, 0xbb,
-	0x35, 0x2f, 0x4a, 0xa5, 0x55, 0x70, 0x99, 0x28, 0x95, 0x64, 0x34, 0x33, 0x68, 0x55, 0xff, 0x9c,
-	0xd1, 0xb6, 0xd0, 0x8d, 0x15, 0xdf, 0x1c, 0x8b, 0x3a, 0xdd, 0x52, 0xa5, 0xe5, 0xb6, 0xe8, 0x0c,
-	0xec, 0xaf, 0x03, 0xde, 0x27, 0xa9, 0xe5, 0x37, 0x95, 0xe6, 0x1a, 0x5f, 0x80, 0x2b, 0x8b, 0xe2,
-	0x47, 0x1a, 0xfb, 0x4e, 0xe8, 0x44, 0x9e, 0x18, 0xcb, 0xa2, 0xb8, 0x8e, 0xf1, 0x12, 0xbc, 0x98,
-	0xee, 0xd2, 0x35, 0xb5, 0xca, 0x89, 0x51, 0x26, 0x1d, 0x71, 0x1d, 0x63, 0x00, 0x93, 0x4c, 0xea,
-	0x54, 0xd7, 0x31, 0xf9, 0xc3, 0xd0, 0x89, 0x1c, 0xb1, 0xc7, 0xf8, 0x12, 0xbc, 0x4c, 0xe5, 0x49,
-	0x27, 0x8e, 0x8c, 0xd8, 0x13, 0xc8, 0x61, 0xd4, 0x9e, 0xe3, 0x8f, 0x43, 0x27, 0x9a, 0xce, 0x03,
-	0xde, 0xdd, 0xca, 0x77, 0xb7, 0xf2, 0xe5, 0xee, 0x56, 0x61, 0x7c, 0xe8, 0xc3, 0x69, 0x21, 0x9b,
-	0x4c, 0xc9, 0xd8, 0x77, 0x43, 0x27, 0x3a, 0x13, 0x3b, 0xc8, 0x5e, 0xc1, 0xe9, 0x0d, 0x35, 0x5f,
-	0xd2, 0x4a, 0x23, 0xc2, 0x68, 0x43, 0x4d, 0xe5, 0x3b, 0xe1, 0x30, 0xf2, 0x84, 0x99, 0xd9, 0x07,
-	0x80, 0x7d, 0xc6, 0x0a, 0x19, 0xb8, 0x85, 0x99, 0x8c, 0x67, 0x3a, 0x07, 0xbe, 0x17, 0x85, 0x55,
-	0xd8, 0x6b, 0x80, 0x05, 0x69, 0x41, 0xbf, 0x6b, 0xaa, 0x34, 0x3e, 0x85, 0xe1, 0x86, 0x1a, 0xdb,
-	0x49, 0x3b, 0xb2, 0xef, 0xf0, 0x5c, 0xc8, 0x38, 0xad, 0xab, 0x5b, 0x92, 0xe5, 0xfa, 0xd7, 0x81,
-	0x31, 0x93, 0xda, 0x18, 0x1d, 0xd1, 0x8e, 0x86, 0xc9, 0x13, 0x53, 0x5a, 0xcb, 0xe4, 0x09, 0x5e,
-	0x80, 0x5b, 0x9a, 0x4f, 0x6d, 0x5b, 0x16, 0xb1, 0x0d, 0x3c, 0x13, 0xb4, 0xd6, 0xf7, 0x17, 0x9e,
-	0xc3, 0xb8, 0x2e, 0xfb, 0x95, 0x1d, 0xb0, 0xec, 0x7e, 0x6d, 0x07, 0x5a, 0x76, 0x95, 0xb5, 0xde,
-	0x6e, 0x6f, 0x07, 0x2c, 0x9b, 0x27, 0xb6, 0xfe, 0x0e, 0xcc, 0xff, 0x9c, 0x80, 0xbb, 0x20, 0xb5,
-	0x5c, 0x7e, 0xc5, 0xf7, 0x30, 0xbe, 0xd5, 0xaa, 0x24, 0x3c, 0xe8, 0x21, 0xb8, 0x78, 0xf0, 0x33,
-	0x3e, 0xb7, 0xaf, 0x8a, 0x0d, 0xf0, 0x0a, 0xce, 0x0e, 0x93, 0xe3, 0x39, 0xff, 0x4f, 0x11, 0xc1,
-	0xb4, 0xdf, 0x55, 0xb1, 0x01, 0xce, 0x00, 0xfa, 0x6c, 0x88, 0xfc, 0x41, 0xd0, 0xe3, 0x0f, 0x42,
-	0x18, 0x2e, 0x48, 0xe3, 0x94, 0xf7, 0x7f, 0x21, 0x38, 0xb8, 0x8f, 0x0d, 0xf0, 0x6d, 0x1b, 0x40,
-	0x7f, 0xcc, 0xb2, 0xfb, 0xa6, 0xa3, 0x3d, 0xef, 0x60, 0x74, 0x43, 0x4d, 0x85, 0x8f, 0xe4, 0x09,
-	0x26, 0xdc, 0xbe, 0x1b, 0x36, 0x58, 0xb9, 0x46, 0xbb, 0xfa, 0x17, 0x00, 0x00, 0xff, 0xff, 0xdc,
-	0x45, 0xa2, 0xd0, 0x62, 0x03, 0x00, 0x00,
-}
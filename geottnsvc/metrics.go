@@ -29,4 +29,20 @@ var (
 			Help:      "The total number of inserts in db",
 		},
 	)
+
+	FenceEventCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "geottn",
+			Name:      "fence_event_total",
+			Help:      "The total number of geofence enter/exit events",
+		},
+	)
+
+	EstimatedCounter = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "geottn",
+			Name:      "estimated_position_total",
+			Help:      "The total number of positions estimated from the gateways",
+		},
+	)
 )
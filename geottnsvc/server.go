@@ -2,65 +2,122 @@ package geottnsvc
 
 import (
 	"context"
-	"errors"
-	"fmt"
+	"sync"
 	"time"
 
 	"github.com/TheThingsNetwork/ttn/core/types"
 	log "github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/status"
 
+	"github.com/akhenakh/geottn/broadcast"
+	"github.com/akhenakh/geottn/decoder"
+	"github.com/akhenakh/geottn/geoloc"
+	"github.com/akhenakh/geottn/ingest"
+	"github.com/akhenakh/geottn/ingest/ttnv2"
 	"github.com/akhenakh/geottn/storage"
 )
 
 type Server struct {
-	appName string
-	logger  log.Logger
-	Health  *health.Server
-	GeoDB   storage.Indexer
-	config  Config
+	appName     string
+	logger      log.Logger
+	Health      *health.Server
+	GeoDB       storage.Indexer
+	Broadcaster *broadcast.Broadcaster
+	config      Config
+	locks       keyLocks
+
+	fencesMu sync.Mutex
+	fences   []fenceRegion
 }
 
+// watchBufferSize is the number of points queued for each watcher
+const watchBufferSize = 128
+
 type Config struct {
-	// the cayenne channel used for gps messages
-	Channel int
+	// Decoders selects the payload decoder of the devices
+	Decoders decoder.Mapping
+
+	// TimeSources is the precedence of the uplink time sources, defaults to DefaultTimeSources
+	TimeSources []string
+
+	// EstimatePosition estimates the position from the receiving gateways for uplinks without gps
+	EstimatePosition bool
 }
 
 func NewServer(appName string, logger log.Logger, idx storage.Indexer, cfg Config) *Server {
 	logger = log.With(logger, "component", "server")
+	if len(cfg.TimeSources) == 0 {
+		cfg.TimeSources = DefaultTimeSources
+	}
 	return &Server{
-		appName: appName,
-		logger:  logger,
-		config:  cfg,
-		GeoDB:   idx,
+		appName:     appName,
+		logger:      logger,
+		config:      cfg,
+		GeoDB:       idx,
+		Broadcaster: broadcast.New(watchBufferSize),
 	}
 }
 
-// HandleMessage handles message from TTN
+// HandleMessage handles message from TTN v2
 func (s *Server) HandleMessage(ctx context.Context, msg *types.UplinkMessage) {
+	s.HandleUplink(ctx, ttnv2.Uplink(msg))
+}
+
+// HandleUplink decodes and stores an uplink received from a network server
+func (s *Server) HandleUplink(ctx context.Context, u *ingest.Uplink) {
 	MsgReceivedCounter.Inc()
-	if msg.PayloadFields == nil {
-		level.Debug(s.logger).Log("msg", "received msg with empty PayloadFields")
+
+	t, src := uplinkTime(u, s.config.TimeSources)
+	meta := uplinkMetadata(u, src)
+
+	name := s.config.Decoders.Name(u.AppID, u.DevID)
+	payload, res, err := decodeUplink(u, name)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "can't decode uplink", "device_id", u.DevID, "decoder", name, "error", err)
+		ErrorCounter.Inc()
 		return
 	}
+	if name != decoder.Default {
+		meta.Decoder = name
+	}
 
-	gpsi, ok := msg.PayloadFields[fmt.Sprintf("gps_%d", s.config.Channel)]
+	lat, lng, ok := res.Lat, res.Lng, res.Position
+	if !ok && s.config.EstimatePosition {
+		var acc float64
+		lat, lng, acc, ok = geoloc.Estimate(meta.Gateways)
+		if ok {
+			meta.Estimated = true
+			meta.Accuracy = acc
+			EstimatedCounter.Inc()
+		}
+	}
 	if !ok {
-		level.Debug(s.logger).Log("msg", "received msg with no gps in PayloadFields")
+		level.Debug(s.logger).Log("msg", "received msg with no position", "device_id", u.DevID, "decoder", name)
 		return
 	}
 
-	gps := gpsi.(map[string]interface{})
-
-	lat := gps["latitude"].(float64)
-	lng := gps["longitude"].(float64)
+	level.Debug(s.logger).Log("msg", "received msg", "device_id", u.DevID, "latitude", lat, "longitude", lng,
+		"time", t, "time_source", src)
 
-	level.Debug(s.logger).Log("msg", "received msg", "device_id", msg.DevID, "latitude", lat, "longitude", lng)
-
-	err := s.GeoDB.Store(msg.DevID, msg.PayloadRaw, lat, lng, time.Now())
+	expired, _, err := s.storePoints([]storage.DataPoint{{
+		Key:   u.DevID,
+		Value: payload,
+		Lat:   lat,
+		Lng:   lng,
+		Time:  t,
+		Meta:  meta,
+	}})
+	if len(expired) > 0 {
+		level.Info(s.logger).Log("msg", "datapoint past the retention", "device_id", u.DevID, "time", t)
+		return
+	}
 	if err != nil {
 		level.Error(s.logger).Log("msg", "can't store datapoint", "error", err)
 		return
@@ -68,28 +125,54 @@ func (s *Server) HandleMessage(ctx context.Context, msg *types.UplinkMessage) {
 	InsertCounter.Inc()
 }
 
+// decodeUplink decodes u with the decoder name, returning the payload to store,
+// the fields decoded by the network server for a FieldsDecoder, the raw payload otherwise
+func decodeUplink(u *ingest.Uplink, name string) ([]byte, *decoder.Result, error) {
+	d, err := decoder.Get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload := u.Payload
+	if fd, ok := d.(decoder.FieldsDecoder); ok {
+		payload, err = fd.Payload(u.Fields)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	res, err := d.Decode(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	return payload, res, nil
+}
+
 func (s *Server) Store(ctx context.Context, dp *DataPoint) (*empty.Empty, error) {
 	e := &empty.Empty{}
-	t, err := ptypes.Timestamp(dp.Time)
-	if err != nil {
+	if _, err := ptypes.Timestamp(dp.Time); err != nil {
 		return e, err
 	}
-	err = s.GeoDB.Store(dp.DeviceId, dp.Payload, dp.Latitude, dp.Longitude, t)
+	expired, _, err := s.storePoints([]storage.DataPoint{*DataPointToStorage(dp)})
 	if err != nil {
 		return e, err
 	}
+	if len(expired) > 0 {
+		return e, status.Error(codes.OutOfRange, storage.ErrExpired.Error())
+	}
 	InsertCounter.Inc()
 	return e, nil
 }
 
 func (s *Server) RadiusSearch(ctx context.Context, req *RadiusSearchRequest) (*DataPoints, error) {
-	dps, err := s.GeoDB.RadiusSearch(req.Lat, req.Lng, req.Radius)
+	dps, next, err := s.GeoDB.RadiusSearchPage(req.Lat, req.Lng, req.Radius, req.Cursor, int(req.Count))
 	if err != nil {
 		return nil, err
 	}
 
 	res := &DataPoints{
-		Points: make([]*DataPoint, len(dps)),
+		Points:     make([]*DataPoint, len(dps)),
+		NextCursor: next,
 	}
 	for i, dp := range dps {
 		res.Points[i] = StorageToDataPoint(&dp)
@@ -98,7 +181,23 @@ func (s *Server) RadiusSearch(ctx context.Context, req *RadiusSearchRequest) (*D
 }
 
 func (s *Server) RectSearch(ctx context.Context, req *RectSearchRequest) (*DataPoints, error) {
-	dps, err := s.GeoDB.RectSearch(req.Urlat, req.Urlng, req.Bllat, req.Bllng)
+	dps, next, err := s.GeoDB.RectSearchPage(req.Urlat, req.Urlng, req.Bllat, req.Bllng, req.Cursor, int(req.Count))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DataPoints{
+		Points:     make([]*DataPoint, len(dps)),
+		NextCursor: next,
+	}
+	for i, dp := range dps {
+		res.Points[i] = StorageToDataPoint(&dp)
+	}
+	return res, nil
+}
+
+func (s *Server) NearestSearch(ctx context.Context, req *NearestSearchRequest) (*DataPoints, error) {
+	dps, err := s.GeoDB.NearestSearch(req.Lat, req.Lng, int(req.Count), req.MaxDistance)
 	if err != nil {
 		return nil, err
 	}
@@ -112,6 +211,101 @@ func (s *Server) RectSearch(ctx context.Context, req *RectSearchRequest) (*DataP
 	return res, nil
 }
 
+func (s *Server) PolygonSearch(ctx context.Context, req *PolygonSearchRequest) (*DataPoints, error) {
+	p, err := PolygonToS2(req.Polygon)
+	if err != nil {
+		return nil, err
+	}
+
+	dps, next, err := s.GeoDB.PolygonSearch(p, req.Cursor, int(req.Count))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DataPoints{
+		Points:     make([]*DataPoint, len(dps)),
+		NextCursor: next,
+	}
+	for i, dp := range dps {
+		res.Points[i] = StorageToDataPoint(&dp)
+	}
+	return res, nil
+}
+
+func (s *Server) RadiusHistorySearch(ctx context.Context, req *RadiusHistorySearchRequest) (*DataPoints, error) {
+	start, end, err := timeRange(req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	dps, next, err := s.GeoDB.RadiusHistorySearch(req.Lat, req.Lng, req.Radius, start, end, req.Cursor, int(req.Count))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DataPoints{
+		Points:     make([]*DataPoint, len(dps)),
+		NextCursor: next,
+	}
+	for i, dp := range dps {
+		res.Points[i] = StorageToDataPoint(&dp)
+	}
+	return res, nil
+}
+
+func (s *Server) RectHistorySearch(ctx context.Context, req *RectHistorySearchRequest) (*DataPoints, error) {
+	start, end, err := timeRange(req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	dps, next, err := s.GeoDB.RectHistorySearch(req.Urlat, req.Urlng, req.Bllat, req.Bllng, start, end, req.Cursor, int(req.Count))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DataPoints{
+		Points:     make([]*DataPoint, len(dps)),
+		NextCursor: next,
+	}
+	for i, dp := range dps {
+		res.Points[i] = StorageToDataPoint(&dp)
+	}
+	return res, nil
+}
+
+func (s *Server) Watch(req *WatchRequest, stream GeoTTN_WatchServer) error {
+	f := broadcast.Filter{Keys: req.Keys}
+	switch {
+	case req.Radius != nil:
+		center := s2.PointFromLatLng(s2.LatLngFromDegrees(req.Radius.Lat, req.Radius.Lng))
+		f.Region = s2.CapFromCenterArea(center, storage.S2RadialAreaMeters(req.Radius.Radius))
+	case req.Rect != nil:
+		rect := s2.RectFromLatLng(s2.LatLngFromDegrees(req.Rect.Bllat, req.Rect.Bllng))
+		f.Region = rect.AddPoint(s2.LatLngFromDegrees(req.Rect.Urlat, req.Rect.Urlng))
+	case req.Polygon != nil:
+		p, err := PolygonToS2(req.Polygon)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		f.Region = p
+	}
+
+	sub := s.Broadcaster.Subscribe(f)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case dp := <-sub.C:
+			if err := stream.Send(StorageToDataPoint(&dp)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func (s *Server) Get(ctx context.Context, req *GetRequest) (*DataPoint, error) {
 	dps, err := s.GeoDB.Get(req.Key)
 	if err != nil {
@@ -130,8 +324,64 @@ func (s *Server) Keys(context.Context, *empty.Empty) (*KeyList, error) {
 	return &KeyList{Keys: keys}, nil
 }
 
-func (s *Server) GetAll(ctx context.Context, in *GetRequest) (*DataPoints, error) {
-	return nil, errors.New("not implemented")
+func (s *Server) Delete(ctx context.Context, req *DeleteRequest) (*empty.Empty, error) {
+	e := &empty.Empty{}
+	if err := s.DeleteDevice(req.Key); err != nil {
+		return e, err
+	}
+	level.Info(s.logger).Log("msg", "deleted device", "device_id", req.Key)
+	return e, nil
+}
+
+// DeleteDevice removes all the data of k, holding its key lock so no point of k is stored meanwhile
+func (s *Server) DeleteDevice(k string) error {
+	defer s.locks.lock([]string{k})()
+	return s.GeoDB.Delete(k)
+}
+
+func (s *Server) GetAll(ctx context.Context, req *GetAllRequest) (*DataPoints, error) {
+	start, end, err := timeRange(req.Start, req.End)
+	if err != nil {
+		return nil, err
+	}
+
+	getRangePage := s.GeoDB.GetRangePage
+	if req.OldestFirst {
+		getRangePage = s.GeoDB.GetRangePageAsc
+	}
+	dps, next, err := getRangePage(req.Key, start, end, req.Cursor, int(req.Count))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &DataPoints{
+		Points:     make([]*DataPoint, len(dps)),
+		NextCursor: next,
+	}
+	for i, dp := range dps {
+		res.Points[i] = StorageToDataPoint(&dp)
+	}
+	return res, nil
+}
+
+// timeRange converts an optional time range, unset boundaries are unbounded
+func timeRange(startTs, endTs *timestamp.Timestamp) (time.Time, time.Time, error) {
+	start, end := storage.MinGeoTime, storage.MaxGeoTime
+	if startTs != nil {
+		t, err := ptypes.Timestamp(startTs)
+		if err != nil {
+			return start, end, err
+		}
+		start = t
+	}
+	if endTs != nil {
+		t, err := ptypes.Timestamp(endTs)
+		if err != nil {
+			return start, end, err
+		}
+		end = t
+	}
+	return start, end, nil
 }
 
 func StorageToDataPoint(dp *storage.DataPoint) *DataPoint {
@@ -145,5 +395,99 @@ func StorageToDataPoint(dp *storage.DataPoint) *DataPoint {
 		Longitude: dp.Lng,
 		Payload:   dp.Value,
 		Time:      t,
+		Distance:  dp.Distance,
+		Meta:      StorageToMetadata(dp.Meta),
+	}
+}
+
+// StorageToMetadata converts a storage Metadata into a Metadata
+func StorageToMetadata(m *storage.Metadata) *Metadata {
+	if m == nil {
+		return nil
+	}
+	res := &Metadata{
+		TimeSource: m.TimeSource,
+		Fcnt:       m.FCnt,
+		DataRate:   m.DataRate,
+		Frequency:  m.Frequency,
+		Gateways:   make([]*GatewayMetadata, len(m.Gateways)),
+		Estimated:  m.Estimated,
+		Accuracy:   m.Accuracy,
+		Decoder:    m.Decoder,
+	}
+	for i, gw := range m.Gateways {
+		res.Gateways[i] = &GatewayMetadata{
+			GatewayId: gw.ID,
+			Rssi:      gw.RSSI,
+			Snr:       gw.SNR,
+			Latitude:  gw.Lat,
+			Longitude: gw.Lng,
+			Altitude:  gw.Alt,
+		}
+	}
+	return res
+}
+
+// MetadataToStorage converts a Metadata into a storage Metadata
+func MetadataToStorage(m *Metadata) *storage.Metadata {
+	if m == nil {
+		return nil
+	}
+	res := &storage.Metadata{
+		TimeSource: m.TimeSource,
+		FCnt:       m.Fcnt,
+		DataRate:   m.DataRate,
+		Frequency:  m.Frequency,
+		Estimated:  m.Estimated,
+		Accuracy:   m.Accuracy,
+		Decoder:    m.Decoder,
+	}
+	for _, gw := range m.Gateways {
+		res.Gateways = append(res.Gateways, storage.GatewayMetadata{
+			ID:   gw.GatewayId,
+			RSSI: gw.Rssi,
+			SNR:  gw.Snr,
+			Lat:  gw.Latitude,
+			Lng:  gw.Longitude,
+			Alt:  gw.Altitude,
+		})
+	}
+	return res
+}
+
+// DataPointToStorage converts a DataPoint into a storage DataPoint
+func DataPointToStorage(dp *DataPoint) *storage.DataPoint {
+	if dp == nil {
+		return nil
+	}
+	t, _ := ptypes.Timestamp(dp.Time)
+	return &storage.DataPoint{
+		Key:      dp.DeviceId,
+		Lat:      dp.Latitude,
+		Lng:      dp.Longitude,
+		Value:    dp.Payload,
+		Time:     t,
+		Distance: dp.Distance,
+		Meta:     MetadataToStorage(dp.Meta),
+	}
+}
+
+// PolygonToS2 converts a Polygon into an s2 polygon
+func PolygonToS2(p *Polygon) (*s2.Polygon, error) {
+	if p == nil {
+		return nil, storage.ErrInvalidPolygon
+	}
+	return storage.NewPolygon(polygonRings(p)...)
+}
+
+// polygonRings returns the rings of p as lat lng
+func polygonRings(p *Polygon) [][]s2.LatLng {
+	rings := make([][]s2.LatLng, len(p.Rings))
+	for i, r := range p.Rings {
+		rings[i] = make([]s2.LatLng, len(r.Points))
+		for j, ll := range r.Points {
+			rings[i][j] = s2.LatLngFromDegrees(ll.Lat, ll.Lng)
+		}
 	}
+	return rings
 }
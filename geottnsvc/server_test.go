@@ -0,0 +1,114 @@
+package geottnsvc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/storage"
+	badgeridx "github.com/akhenakh/geottn/storage/badger"
+)
+
+// newTestServer returns a Server with the default config on a temporary database,
+// opts can change the database options, clean closes and removes the database
+func newTestServer(t *testing.T, opts ...func(badger.Options) badger.Options) (*Server, *badgeridx.Indexer, func()) {
+	dir, err := ioutil.TempDir("", "badger")
+	require.NoError(t, err)
+
+	bopts := badger.DefaultOptions(dir).WithLogger(nil)
+	for _, opt := range opts {
+		bopts = opt(bopts)
+	}
+	bdb, err := badger.Open(bopts)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	idx := &badgeridx.Indexer{DB: bdb}
+	s := NewServer("test", log.NewNopLogger(), idx, Config{})
+	return s, idx, func() {
+		bdb.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// storePoint stores a point without metadata, storage.ErrExpired is returned for a point already past the retention
+func (s *Server) storePoint(k string, v []byte, lat, lng float64, t time.Time) error {
+	expired, _, err := s.storePoints([]storage.DataPoint{{Key: k, Value: v, Lat: lat, Lng: lng, Time: t}})
+	if err != nil {
+		return err
+	}
+	if len(expired) > 0 {
+		return storage.ErrExpired
+	}
+	return nil
+}
+
+func TestStoreMeta(t *testing.T) {
+	s, _, clean := newTestServer(t)
+	defer clean()
+
+	ts, err := ptypes.TimestampProto(time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	_, err = s.Store(context.Background(), &DataPoint{
+		DeviceId:  "KEY",
+		Latitude:  48.8,
+		Longitude: 2.2,
+		Time:      ts,
+		Meta: &Metadata{
+			Fcnt:     42,
+			Gateways: []*GatewayMetadata{{GatewayId: "gw1", Rssi: -90, Snr: 7}},
+		},
+	})
+	require.NoError(t, err)
+
+	res, err := s.GetAll(context.Background(), &GetAllRequest{Key: "KEY"})
+	require.NoError(t, err)
+	require.Len(t, res.Points, 1)
+	require.NotNil(t, res.Points[0].Meta)
+	require.Equal(t, uint32(42), res.Points[0].Meta.Fcnt)
+	require.Equal(t, "gw1", res.Points[0].Meta.Gateways[0].GatewayId)
+}
+
+func TestDeleteDeviceConcurrent(t *testing.T) {
+	s, idx, clean := newTestServer(t)
+	defer clean()
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				pt := ts.Add(time.Duration(i*50+j) * time.Second)
+				require.NoError(t, s.storePoint("KEY", nil, 48.8, 2.2, pt))
+			}
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.DeleteDevice("KEY"))
+	}
+	wg.Wait()
+
+	// the device is either listed with its history or entirely gone
+	keys, err := idx.Keys()
+	require.NoError(t, err)
+	dps, err := idx.GetAll("KEY", 0)
+	require.NoError(t, err)
+	require.Equal(t, len(keys) == 1, len(dps) > 0)
+
+	require.NoError(t, s.DeleteDevice("KEY"))
+	dps, err = idx.GetAll("KEY", 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 0)
+}
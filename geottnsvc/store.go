@@ -0,0 +1,281 @@
+package geottnsvc
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"io"
+	"sync"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/golang/geo/s2"
+	"github.com/golang/protobuf/ptypes"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+// storeBatchSize is the number of points stored per transaction when batching
+const storeBatchSize = 1000
+
+// keyLocksSize is the number of mutexes the device keys are spread over
+const keyLocksSize = 64
+
+// errInvalidPoint is returned for points without a key, a valid position or time
+var errInvalidPoint = errors.New("invalid data point")
+
+// StoreBatch stores the points in batches, invalid or failing points are reported in the summary
+func (s *Server) StoreBatch(ctx context.Context, req *StoreBatchRequest) (*StoreSummary, error) {
+	res := &StoreSummary{}
+	for i := 0; i < len(req.Points); i += storeBatchSize {
+		end := i + storeBatchSize
+		if end > len(req.Points) {
+			end = len(req.Points)
+		}
+		s.storeBatch(req.Points[i:end], int64(i), res)
+	}
+
+	level.Debug(s.logger).Log("msg", "stored batch", "stored", res.Stored, "rejected", res.Rejected)
+	return res, nil
+}
+
+// StoreStream stores the streamed points in batches, invalid or failing points are reported in the summary
+func (s *Server) StoreStream(stream GeoTTN_StoreStreamServer) error {
+	res := &StoreSummary{}
+	batch := make([]*DataPoint, 0, storeBatchSize)
+	var offset int64
+
+	for {
+		dp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, dp)
+		if len(batch) >= storeBatchSize {
+			s.storeBatch(batch, offset, res)
+			offset += int64(len(batch))
+			batch = batch[:0]
+		}
+	}
+	s.storeBatch(batch, offset, res)
+
+	level.Info(s.logger).Log("msg", "stored stream", "stored", res.Stored, "rejected", res.Rejected)
+	return stream.SendAndClose(res)
+}
+
+// storeBatch stores the valid points of batch in a transaction and updates res,
+// offset is the index of the first point in the whole request
+func (s *Server) storeBatch(batch []*DataPoint, offset int64, res *StoreSummary) {
+	reject := func(i int, err error) {
+		res.Rejected++
+		res.Errors = append(res.Errors, &StoreError{Index: offset + int64(i), Message: err.Error()})
+	}
+
+	dps := make([]storage.DataPoint, 0, len(batch))
+	indexes := make([]int, 0, len(batch))
+	for i, dp := range batch {
+		if err := validDataPoint(dp); err != nil {
+			reject(i, err)
+			continue
+		}
+		dps = append(dps, *DataPointToStorage(dp))
+		indexes = append(indexes, i)
+	}
+	if len(dps) == 0 {
+		return
+	}
+
+	expired, n, err := s.storePoints(dps)
+	for _, j := range expired {
+		reject(indexes[j], storage.ErrExpired)
+	}
+	InsertCounter.Add(float64(n - len(expired)))
+	res.Stored += int64(n - len(expired))
+	if err == nil {
+		return
+	}
+
+	// retry the points following the committed ones one by one to find the failing points
+	for j := n; j < len(dps); j++ {
+		expired, _, err := s.storePoints(dps[j : j+1])
+		if err != nil {
+			level.Error(s.logger).Log("msg", "can't store datapoint", "device_id", dps[j].Key, "error", err)
+			ErrorCounter.Inc()
+			reject(indexes[j], err)
+			continue
+		}
+		if len(expired) > 0 {
+			reject(indexes[j], storage.ErrExpired)
+			continue
+		}
+		InsertCounter.Inc()
+		res.Stored++
+	}
+}
+
+// storePoints stores dps in a single transaction, records the geofences transitions
+// comparing with the previous positions, then publishes them to the watchers,
+// the batch is split in halves when too big for one transaction,
+// it returns the indexes of the points not stored because already past the retention
+// and n, the number of leading points of dps committed or expired, len(dps) without error
+func (s *Server) storePoints(dps []storage.DataPoint) (expired []int, n int, err error) {
+	expired, err = s.storePointsTx(dps)
+	if err == nil {
+		return expired, len(dps), nil
+	}
+	if err != storage.ErrTxTooBig || len(dps) < 2 {
+		return nil, 0, err
+	}
+
+	level.Debug(s.logger).Log("msg", "splitting batch", "size", len(dps))
+	half := len(dps) / 2
+	expired, n, err = s.storePoints(dps[:half])
+	if err != nil {
+		return expired, n, err
+	}
+	expired2, n, err := s.storePoints(dps[half:])
+	for _, j := range expired2 {
+		expired = append(expired, half+j)
+	}
+	return expired, half + n, err
+}
+
+func (s *Server) storePointsTx(dps []storage.DataPoint) ([]int, error) {
+	keys := make([]string, len(dps))
+	for i := range dps {
+		keys[i] = dps[i].Key
+	}
+	// the previous positions are read outside of the transaction,
+	// concurrent writes for the same keys must wait for the commit
+	defer s.locks.lock(keys)()
+
+	fences, err := s.fenceRegions()
+	if err != nil {
+		return nil, err
+	}
+
+	// current positions, updated while going through the batch
+	positions := make(map[string]*storage.DataPoint)
+
+	tx := s.GeoDB.Begin()
+	defer tx.Discard()
+
+	var expired []int
+	var events int
+	for i := range dps {
+		dp := &dps[i]
+
+		prev, ok := positions[dp.Key]
+		if !ok {
+			prev, err = s.GeoDB.Get(dp.Key)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = s.GeoDB.StoreTx(tx, dp.Key, dp.Value, dp.Lat, dp.Lng, dp.Time)
+		if err == storage.ErrExpired {
+			expired = append(expired, i)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if dp.Meta != nil {
+			if err := s.GeoDB.StoreMetaTx(tx, dp.Key, dp.Lat, dp.Lng, dp.Time, dp.Meta); err != nil {
+				return nil, err
+			}
+		}
+
+		// an older point does not change the current position
+		if prev != nil && !dp.Time.After(prev.Time) {
+			positions[dp.Key] = prev
+			continue
+		}
+		positions[dp.Key] = dp
+
+		p := s2.PointFromLatLng(s2.LatLngFromDegrees(dp.Lat, dp.Lng))
+		for _, f := range fences {
+			in := f.Region.ContainsPoint(p)
+			wasIn := prev != nil && f.Region.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(prev.Lat, prev.Lng)))
+			if in == wasIn {
+				continue
+			}
+
+			e := &storage.GeofenceEvent{
+				FenceID: f.ID,
+				Key:     dp.Key,
+				Type:    storage.FenceEnter,
+				Time:    dp.Time,
+				Lat:     dp.Lat,
+				Lng:     dp.Lng,
+			}
+			if !in {
+				e.Type = storage.FenceExit
+			}
+			if err := s.GeoDB.StoreFenceEventTx(tx, e); err != nil {
+				return nil, err
+			}
+
+			level.Debug(s.logger).Log("msg", "fence event", "fence_id", f.ID, "device_id", dp.Key, "type", e.Type)
+			events++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	FenceEventCounter.Add(float64(events))
+	skip := expired
+	for i, dp := range dps {
+		if len(skip) > 0 && skip[0] == i {
+			skip = skip[1:]
+			continue
+		}
+		s.Broadcaster.Publish(dp)
+	}
+	return expired, nil
+}
+
+// keyLocks serialises the writes per device key
+type keyLocks [keyLocksSize]sync.Mutex
+
+// lock locks the mutexes of keys, always in the same order, and returns the unlock func
+func (l *keyLocks) lock(keys []string) func() {
+	var set [keyLocksSize]bool
+	for _, k := range keys {
+		h := fnv.New32a()
+		h.Write([]byte(k))
+		set[h.Sum32()%keyLocksSize] = true
+	}
+
+	for i := range l {
+		if set[i] {
+			l[i].Lock()
+		}
+	}
+	return func() {
+		for i := range l {
+			if set[i] {
+				l[i].Unlock()
+			}
+		}
+	}
+}
+
+// validDataPoint checks dp has a key, a valid position and time
+func validDataPoint(dp *DataPoint) error {
+	if dp.DeviceId == "" ||
+		dp.Latitude < -90 || dp.Latitude > 90 ||
+		dp.Longitude < -180 || dp.Longitude > 180 {
+		return errInvalidPoint
+	}
+	if _, err := ptypes.Timestamp(dp.Time); err != nil {
+		return errInvalidPoint
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package geottnsvc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/akhenakh/geottn/broadcast"
+	"github.com/akhenakh/geottn/storage"
+)
+
+// fakeStoreStream replays points as a client stream
+type fakeStoreStream struct {
+	grpc.ServerStream
+	points []*DataPoint
+	res    *StoreSummary
+}
+
+func (f *fakeStoreStream) Context() context.Context {
+	return context.Background()
+}
+
+func (f *fakeStoreStream) Recv() (*DataPoint, error) {
+	if len(f.points) == 0 {
+		return nil, io.EOF
+	}
+	dp := f.points[0]
+	f.points = f.points[1:]
+	return dp, nil
+}
+
+func (f *fakeStoreStream) SendAndClose(res *StoreSummary) error {
+	f.res = res
+	return nil
+}
+
+func TestStoreStream(t *testing.T) {
+	// a small table size to force the batches to be split
+	s, idx, clean := newTestServer(t, func(o badger.Options) badger.Options {
+		return o.WithMaxTableSize(1 << 16)
+	})
+	defer clean()
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	stream := &fakeStoreStream{}
+	for i := 0; i < storeBatchSize+10; i++ {
+		pt, _ := ptypes.TimestampProto(ts.Add(time.Duration(i) * time.Second))
+		stream.points = append(stream.points, &DataPoint{
+			DeviceId:  "KEY",
+			Latitude:  48.8,
+			Longitude: 2.2 + float64(i)*0.0001,
+			Time:      pt,
+		})
+	}
+	// invalid points
+	stream.points = append(stream.points,
+		&DataPoint{Latitude: 48.8, Longitude: 2.2, Time: ptypes.TimestampNow()},
+		&DataPoint{DeviceId: "KEY", Latitude: 98.8, Longitude: 2.2, Time: ptypes.TimestampNow()},
+		&DataPoint{DeviceId: "KEY", Latitude: 48.8, Longitude: 2.2},
+	)
+
+	require.NoError(t, s.StoreStream(stream))
+	require.Equal(t, int64(storeBatchSize+10), stream.res.Stored)
+	require.Equal(t, int64(3), stream.res.Rejected)
+	require.Len(t, stream.res.Errors, 3)
+	require.Equal(t, int64(storeBatchSize+10), stream.res.Errors[0].Index)
+	require.Equal(t, int64(storeBatchSize+12), stream.res.Errors[2].Index)
+
+	dps, err := idx.GetAll("KEY", 0)
+	require.NoError(t, err)
+	require.Len(t, dps, storeBatchSize+10)
+
+	dp, err := idx.Get("KEY")
+	require.NoError(t, err)
+	require.Equal(t, ts.Add(time.Duration(storeBatchSize+9)*time.Second), dp.Time)
+
+	// only the most recent point is in the geo index
+	dps, err = idx.RadiusSearch(48.8, 2.2, 100000)
+	require.NoError(t, err)
+	require.Len(t, dps, 1)
+
+}
+
+func TestStoreBatch(t *testing.T) {
+	s, idx, clean := newTestServer(t)
+	defer clean()
+
+	sub := s.Broadcaster.Subscribe(broadcast.Filter{Keys: []string{"KEY2"}})
+	defer sub.Close()
+
+	req := &StoreBatchRequest{Points: []*DataPoint{
+		{DeviceId: "KEY", Latitude: 48.8, Longitude: 2.2, Time: ptypes.TimestampNow()},
+		{DeviceId: "KEY", Latitude: 48.8, Longitude: 200, Time: ptypes.TimestampNow()},
+		{DeviceId: "KEY2", Latitude: 48.9, Longitude: 2.2, Time: ptypes.TimestampNow()},
+	}}
+	res, err := s.StoreBatch(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), res.Stored)
+	require.Equal(t, int64(1), res.Rejected)
+	require.Len(t, res.Errors, 1)
+	require.Equal(t, int64(1), res.Errors[0].Index)
+
+	keys, err := idx.Keys()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	dp := <-sub.C
+	require.Equal(t, "KEY2", dp.Key)
+}
+
+func TestStoreBatchRetry(t *testing.T) {
+	// a small table size to force the batch to be split, a small value log to refuse a large payload
+	s, idx, clean := newTestServer(t, func(o badger.Options) badger.Options {
+		return o.WithMaxTableSize(1 << 16).WithValueLogFileSize(1 << 20)
+	})
+	defer clean()
+	s.Broadcaster = broadcast.New(storeBatchSize)
+	sub := s.Broadcaster.Subscribe(broadcast.Filter{Keys: []string{"KEY"}})
+	defer sub.Close()
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	req := &StoreBatchRequest{}
+	for i := 0; i < storeBatchSize; i++ {
+		pt, _ := ptypes.TimestampProto(ts.Add(time.Duration(i) * time.Second))
+		req.Points = append(req.Points, &DataPoint{DeviceId: "KEY", Latitude: 48.8, Longitude: 2.2, Time: pt})
+	}
+	req.Points[storeBatchSize-2].Payload = make([]byte, 2<<20)
+
+	res, err := s.StoreBatch(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int64(storeBatchSize-1), res.Stored)
+	require.Equal(t, int64(1), res.Rejected)
+	require.Equal(t, int64(storeBatchSize-2), res.Errors[0].Index)
+
+	// the points committed before the failure are published once
+	require.Len(t, sub.C, storeBatchSize-1)
+
+	dps, err := idx.GetAll("KEY", 0)
+	require.NoError(t, err)
+	require.Len(t, dps, storeBatchSize-1)
+}
+
+func TestStoreBatchExpired(t *testing.T) {
+	s, idx, clean := newTestServer(t)
+	defer clean()
+	idx.Retention = time.Hour
+
+	old, err := ptypes.TimestampProto(time.Now().Add(-2 * time.Hour))
+	require.NoError(t, err)
+	req := &StoreBatchRequest{Points: []*DataPoint{
+		{DeviceId: "KEY", Latitude: 48.8, Longitude: 2.2, Time: old},
+		{DeviceId: "KEY", Latitude: 48.8, Longitude: 2.2, Time: ptypes.TimestampNow()},
+		{DeviceId: "KEY2", Latitude: 48.9, Longitude: 2.2, Time: old},
+	}}
+	res, err := s.StoreBatch(context.Background(), req)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), res.Stored)
+	require.Equal(t, int64(2), res.Rejected)
+	require.Len(t, res.Errors, 2)
+	require.Equal(t, int64(0), res.Errors[0].Index)
+	require.Equal(t, storage.ErrExpired.Error(), res.Errors[0].Message)
+	require.Equal(t, int64(2), res.Errors[1].Index)
+
+	keys, err := idx.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"KEY"}, keys)
+
+	_, err = s.Store(context.Background(), req.Points[0])
+	require.Equal(t, codes.OutOfRange, status.Code(err))
+}
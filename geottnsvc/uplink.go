@@ -0,0 +1,82 @@
+package geottnsvc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/akhenakh/geottn/ingest"
+	"github.com/akhenakh/geottn/storage"
+)
+
+// DefaultTimeSources is the default precedence of the uplink time sources
+var DefaultTimeSources = []string{storage.TimeSourceGateway, storage.TimeSourceNetwork, storage.TimeSourceLocal}
+
+// ParseTimeSources parses a list of time sources separated by commas
+func ParseTimeSources(s string) ([]string, error) {
+	var res []string
+	for _, src := range strings.Split(s, ",") {
+		src = strings.TrimSpace(src)
+		switch src {
+		case storage.TimeSourceGateway, storage.TimeSourceNetwork, storage.TimeSourceLocal:
+			res = append(res, src)
+		default:
+			return nil, fmt.Errorf("invalid time source %q", src)
+		}
+	}
+	return res, nil
+}
+
+// uplinkTime returns the time of u from the first available source,
+// falling back to the local time
+func uplinkTime(u *ingest.Uplink, sources []string) (time.Time, string) {
+	for _, src := range sources {
+		switch src {
+		case storage.TimeSourceGateway:
+			// the earliest reception, gateways without GPS may not report a time
+			var gt time.Time
+			for _, gw := range u.Gateways {
+				t := gw.Time
+				if validTime(t) && (gt.IsZero() || t.Before(gt)) {
+					gt = t
+				}
+			}
+			if !gt.IsZero() {
+				return gt.UTC(), src
+			}
+		case storage.TimeSourceNetwork:
+			if t := u.Time; validTime(t) {
+				return t.UTC(), src
+			}
+		case storage.TimeSourceLocal:
+			return time.Now().UTC(), src
+		}
+	}
+	return time.Now().UTC(), storage.TimeSourceLocal
+}
+
+// uplinkMetadata returns the radio metadata of u, timeSource is the source used for the point time
+func uplinkMetadata(u *ingest.Uplink, timeSource string) *storage.Metadata {
+	m := &storage.Metadata{
+		TimeSource: timeSource,
+		FCnt:       u.FCnt,
+		DataRate:   u.DataRate,
+		Frequency:  u.Frequency,
+	}
+	for _, gw := range u.Gateways {
+		m.Gateways = append(m.Gateways, storage.GatewayMetadata{
+			ID:   gw.ID,
+			RSSI: gw.RSSI,
+			SNR:  gw.SNR,
+			Lat:  gw.Lat,
+			Lng:  gw.Lng,
+			Alt:  gw.Alt,
+		})
+	}
+	return m
+}
+
+// validTime reports whether t is set, TTN uses the zero time and the unix epoch for unset times
+func validTime(t time.Time) bool {
+	return !t.IsZero() && t.Unix() != 0
+}
@@ -0,0 +1,173 @@
+package geottnsvc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/decoder"
+	"github.com/akhenakh/geottn/ingest/chirpstack"
+	"github.com/akhenakh/geottn/ingest/ttnv2"
+	"github.com/akhenakh/geottn/storage"
+)
+
+func TestUplinkTime(t *testing.T) {
+	gwt := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	nst := gwt.Add(time.Second)
+	msg := &types.UplinkMessage{}
+	msg.Metadata.Time = types.JSONTime(nst)
+	msg.Metadata.Gateways = []types.GatewayMetadata{
+		{GtwID: "nogps"},
+		{GtwID: "gw2", Time: types.JSONTime(gwt.Add(time.Millisecond))},
+		{GtwID: "gw1", Time: types.JSONTime(gwt)},
+	}
+
+	ts, src := uplinkTime(ttnv2.Uplink(msg), DefaultTimeSources)
+	require.Equal(t, gwt, ts)
+	require.Equal(t, storage.TimeSourceGateway, src)
+
+	ts, src = uplinkTime(ttnv2.Uplink(msg), []string{storage.TimeSourceNetwork, storage.TimeSourceGateway})
+	require.Equal(t, nst, ts)
+	require.Equal(t, storage.TimeSourceNetwork, src)
+
+	// no gateway time, no network time, falling back to local
+	msg.Metadata = types.Metadata{Time: types.JSONTime(time.Unix(0, 0))}
+	ts, src = uplinkTime(ttnv2.Uplink(msg), []string{storage.TimeSourceGateway, storage.TimeSourceNetwork})
+	require.Equal(t, storage.TimeSourceLocal, src)
+	require.WithinDuration(t, time.Now(), ts, time.Second)
+
+	_, err := ParseTimeSources("network,gps")
+	require.Error(t, err)
+	sources, err := ParseTimeSources("network, local")
+	require.NoError(t, err)
+	require.Equal(t, []string{storage.TimeSourceNetwork, storage.TimeSourceLocal}, sources)
+}
+
+func TestHandleMessageMetadata(t *testing.T) {
+	s, _, clean := newTestServer(t)
+	defer clean()
+
+	gwt := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	msg := &types.UplinkMessage{
+		DevID:      "KEY",
+		FCnt:       42,
+		PayloadRaw: []byte{1, 136, 7, 169, 32, 0, 214, 216, 0, 0, 0},
+	}
+	msg.Metadata.DataRate = "SF7BW125"
+	msg.Metadata.Frequency = 868.1
+	msg.Metadata.Gateways = []types.GatewayMetadata{
+		{GtwID: "gw1", Time: types.JSONTime(gwt), RSSI: -110, SNR: -2.5},
+	}
+	msg.Metadata.Gateways[0].Latitude = 48.85
+
+	s.HandleMessage(context.Background(), msg)
+
+	res, err := s.GetAll(context.Background(), &GetAllRequest{Key: "KEY"})
+	require.NoError(t, err)
+	require.Len(t, res.Points, 1)
+	dp := res.Points[0]
+	require.Equal(t, gwt.Unix(), dp.Time.Seconds)
+	require.NotNil(t, dp.Meta)
+	require.Equal(t, storage.TimeSourceGateway, dp.Meta.TimeSource)
+	require.Equal(t, uint32(42), dp.Meta.Fcnt)
+	require.Equal(t, "SF7BW125", dp.Meta.DataRate)
+	require.Len(t, dp.Meta.Gateways, 1)
+	require.Equal(t, "gw1", dp.Meta.Gateways[0].GatewayId)
+	require.Equal(t, float32(-110), dp.Meta.Gateways[0].Rssi)
+	require.InDelta(t, 48.85, dp.Meta.Gateways[0].Latitude, 0.0001)
+}
+
+func TestHandleMessageEstimate(t *testing.T) {
+	s, idx, clean := newTestServer(t)
+	defer clean()
+
+	msg := &types.UplinkMessage{DevID: "KEY", PayloadRaw: []byte{3, 103, 1, 16}}
+	msg.Metadata.Gateways = []types.GatewayMetadata{{GtwID: "gw1", RSSI: -90}}
+	msg.Metadata.Gateways[0].Latitude = 48.85
+	msg.Metadata.Gateways[0].Longitude = 2.35
+
+	// estimation disabled, the uplink is dropped
+	s.HandleMessage(context.Background(), msg)
+	dp, err := idx.Get("KEY")
+	require.NoError(t, err)
+	require.Nil(t, dp)
+
+	s.config.EstimatePosition = true
+	s.HandleMessage(context.Background(), msg)
+
+	res, err := s.GetAll(context.Background(), &GetAllRequest{Key: "KEY"})
+	require.NoError(t, err)
+	require.Len(t, res.Points, 1)
+	require.InDelta(t, 48.85, res.Points[0].Latitude, 0.0001)
+	require.InDelta(t, 2.35, res.Points[0].Longitude, 0.0001)
+	require.True(t, res.Points[0].Meta.Estimated)
+	require.True(t, res.Points[0].Meta.Accuracy > 0)
+}
+
+func TestHandleMessageDecoder(t *testing.T) {
+	s, _, clean := newTestServer(t)
+	defer clean()
+	s.config.Decoders = decoder.Mapping{
+		Apps:    map[string]string{"trackers": "fields"},
+		Devices: map[string]string{"CUSTOM": "json"},
+	}
+
+	msg := &types.UplinkMessage{
+		AppID:         "trackers",
+		DevID:         "DRAGINO",
+		PayloadRaw:    []byte{0xca, 0xfe},
+		PayloadFields: map[string]interface{}{"latitude": 48.8, "longitude": 2.2, "BatV": 3.1},
+	}
+	s.HandleMessage(context.Background(), msg)
+
+	msg = &types.UplinkMessage{
+		AppID:      "trackers",
+		DevID:      "CUSTOM",
+		PayloadRaw: []byte(`{"latitude":"44.8","longitude":"1.2"}`),
+	}
+	s.HandleMessage(context.Background(), msg)
+
+	res, err := s.GetAll(context.Background(), &GetAllRequest{Key: "DRAGINO"})
+	require.NoError(t, err)
+	require.Len(t, res.Points, 1)
+	require.InDelta(t, 48.8, res.Points[0].Latitude, 0.0001)
+	require.Equal(t, "fields", res.Points[0].Meta.Decoder)
+
+	dec, err := decoder.Decode(res.Points[0].Meta.Decoder, res.Points[0].Payload)
+	require.NoError(t, err)
+	require.Equal(t, 3.1, dec.Values["BatV"])
+
+	res, err = s.GetAll(context.Background(), &GetAllRequest{Key: "CUSTOM"})
+	require.NoError(t, err)
+	require.Len(t, res.Points, 1)
+	require.InDelta(t, 44.8, res.Points[0].Latitude, 0.0001)
+	require.Equal(t, "json", res.Points[0].Meta.Decoder)
+}
+
+func TestHandleUplinkChirpStack(t *testing.T) {
+	s, _, clean := newTestServer(t)
+	defer clean()
+
+	// cayenne gps on channel 1, as decoded by the default decoder
+	u, err := chirpstack.Decode([]byte(`{
+		"applicationID": "12", "devEUI": "cHBwcHBwcAE=", "fCnt": 42, "data": "AYgHqSAA1tgAAAA=",
+		"object": {"gpsLocation": {"1": {"latitude": 50.2048, "longitude": 5.5, "altitude": 0}}},
+		"rxInfo": [{"gatewayID": "uCfr//4AAAE=", "time": "2020-11-22T10:00:01Z", "rssi": -110, "loRaSNR": -2.5}],
+		"txInfo": {"frequency": 868100000, "loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 7}}
+	}`))
+	require.NoError(t, err)
+	s.HandleUplink(context.Background(), u)
+
+	res, err := s.GetAll(context.Background(), &GetAllRequest{Key: "7070707070707001"})
+	require.NoError(t, err)
+	require.Len(t, res.Points, 1)
+	dp := res.Points[0]
+	require.InDelta(t, 50.2048, dp.Latitude, 0.0001)
+	require.InDelta(t, 5.5, dp.Longitude, 0.0001)
+	require.Equal(t, storage.TimeSourceGateway, dp.Meta.TimeSource)
+	require.Equal(t, "SF7BW125", dp.Meta.DataRate)
+	require.Equal(t, "b827ebfffe000001", dp.Meta.Gateways[0].GatewayId)
+}
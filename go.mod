@@ -8,6 +8,7 @@ require (
 	github.com/akhenakh/cayenne v0.0.0-20191121201738-ad831dca3b86
 	github.com/armon/go-metrics v0.3.0 // indirect
 	github.com/dgraph-io/badger/v2 v2.0.0
+	github.com/eclipse/paho.mqtt.golang v1.2.0
 	github.com/go-kit/kit v0.9.0
 	github.com/gobuffalo/envy v1.8.1 // indirect
 	github.com/gobuffalo/logger v1.0.2 // indirect
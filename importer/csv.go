@@ -0,0 +1,90 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+// CSVReader reads rows with a header naming the time, latitude (or lat) and longitude (or lng, lon) columns,
+// and an optional device_id column, other columns are ignored, as written by the csv export
+type CSVReader struct {
+	r   *csv.Reader
+	key string
+	row int
+
+	keyCol, timeCol, latCol, lngCol int
+}
+
+// NewCSVReader reads the header from r
+func NewCSVReader(r io.Reader, key string) (*CSVReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	res := &CSVReader{r: cr, key: key, row: 1, keyCol: -1, timeCol: -1, latCol: -1, lngCol: -1}
+	for i, h := range header {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "device_id":
+			res.keyCol = i
+		case "time":
+			res.timeCol = i
+		case "latitude", "lat":
+			res.latCol = i
+		case "longitude", "lng", "lon":
+			res.lngCol = i
+		}
+	}
+	if res.timeCol < 0 || res.latCol < 0 || res.lngCol < 0 {
+		return nil, errors.New("csv header requires time, latitude and longitude columns")
+	}
+	return res, nil
+}
+
+func (r *CSVReader) Read() (*storage.DataPoint, error) {
+	rec, err := r.r.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	r.row++
+	if err != nil {
+		// a malformed line, the csv reader can continue with the next one
+		if _, ok := err.(*csv.ParseError); ok {
+			return nil, &RowError{Row: r.row, Err: err}
+		}
+		return nil, err
+	}
+
+	field := func(i int) string {
+		if i < 0 || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	key := field(r.keyCol)
+	if key == "" {
+		key = r.key
+	}
+	lat, err := strconv.ParseFloat(field(r.latCol), 64)
+	if err != nil {
+		return nil, &RowError{Row: r.row, Err: err}
+	}
+	lng, err := strconv.ParseFloat(field(r.lngCol), 64)
+	if err != nil {
+		return nil, &RowError{Row: r.row, Err: err}
+	}
+
+	dp, err := point(key, lat, lng, field(r.timeCol))
+	if err != nil {
+		return nil, &RowError{Row: r.row, Err: err}
+	}
+	return dp, nil
+}
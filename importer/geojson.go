@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/twpayne/go-geom"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+// GeoJSONReader reads the Point features of a FeatureCollection,
+// the time is read from the time or ts property, the device from the device_id property
+type GeoJSONReader struct {
+	features []*geojson.Feature
+	key      string
+	row      int
+}
+
+// NewGeoJSONReader decodes the whole FeatureCollection from r
+func NewGeoJSONReader(r io.Reader, key string) (*GeoJSONReader, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := &geojson.FeatureCollection{}
+	if err := fc.UnmarshalJSON(b); err != nil {
+		return nil, err
+	}
+	return &GeoJSONReader{features: fc.Features, key: key}, nil
+}
+
+func (r *GeoJSONReader) Read() (*storage.DataPoint, error) {
+	if r.row >= len(r.features) {
+		return nil, io.EOF
+	}
+	f := r.features[r.row]
+	r.row++
+
+	p, ok := f.Geometry.(*geom.Point)
+	if !ok {
+		return nil, &RowError{Row: r.row, Err: errors.New("expecting a Point")}
+	}
+
+	key := r.key
+	if v, ok := f.Properties["device_id"].(string); ok && v != "" {
+		key = v
+	}
+
+	var t string
+	for _, prop := range []string{"time", "ts"} {
+		if v, ok := f.Properties[prop]; ok {
+			t = fmt.Sprint(v)
+			break
+		}
+	}
+
+	dp, err := point(key, p.Y(), p.X(), t)
+	if err != nil {
+		return nil, &RowError{Row: r.row, Err: err}
+	}
+	return dp, nil
+}
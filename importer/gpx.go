@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"encoding/xml"
+	"io"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+// GPXReader reads the track, route and way points of a GPX document
+type GPXReader struct {
+	dec *xml.Decoder
+	key string
+	row int
+}
+
+type gpxPoint struct {
+	Lat  float64 `xml:"lat,attr"`
+	Lon  float64 `xml:"lon,attr"`
+	Time string  `xml:"time"`
+}
+
+// NewGPXReader returns a GPXReader for the device key
+func NewGPXReader(r io.Reader, key string) *GPXReader {
+	return &GPXReader{dec: xml.NewDecoder(r), key: key}
+}
+
+func (r *GPXReader) Read() (*storage.DataPoint, error) {
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "trkpt", "rtept", "wpt":
+		default:
+			continue
+		}
+
+		r.row++
+		var p gpxPoint
+		if err := r.dec.DecodeElement(&p, &se); err != nil {
+			return nil, err
+		}
+
+		dp, err := point(r.key, p.Lat, p.Lon, p.Time)
+		if err != nil {
+			return nil, &RowError{Row: r.row, Err: err}
+		}
+		return dp, nil
+	}
+}
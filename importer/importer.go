@@ -0,0 +1,73 @@
+// Package importer reads data points history from files, the counterpart of the export package
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+// ErrUnknownFormat is returned when asking for an unsupported format
+var ErrUnknownFormat = errors.New("unknown import format")
+
+// Formats lists the supported import formats
+var Formats = []string{"csv", "geojson", "gpx"}
+
+// Reader reads data points one by one
+type Reader interface {
+	// Read returns the next point, io.EOF at the end, a *RowError for an invalid entry
+	// after which reading can continue
+	Read() (*storage.DataPoint, error)
+}
+
+// RowError reports an invalid entry
+type RowError struct {
+	Row int
+	Err error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+// NewReader returns a Reader for format reading from r,
+// key is the device used when the file does not provide one
+func NewReader(format string, r io.Reader, key string) (Reader, error) {
+	switch format {
+	case "csv":
+		return NewCSVReader(r, key)
+	case "geojson":
+		return NewGeoJSONReader(r, key)
+	case "gpx":
+		return NewGPXReader(r, key), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// FormatFromPath returns the format matching the file extension of path
+func FormatFromPath(path string) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if ext == "json" {
+		return "geojson"
+	}
+	return ext
+}
+
+// point validates and returns a data point
+func point(key string, lat, lng float64, t string) (*storage.DataPoint, error) {
+	if key == "" {
+		return nil, errors.New("missing device id")
+	}
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+		return nil, fmt.Errorf("invalid position %f %f", lat, lng)
+	}
+	ts, err := parseTime(t)
+	if err != nil {
+		return nil, err
+	}
+	return &storage.DataPoint{Key: key, Lat: lat, Lng: lng, Time: ts}, nil
+}
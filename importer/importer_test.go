@@ -0,0 +1,104 @@
+package importer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/export"
+	"github.com/akhenakh/geottn/storage"
+)
+
+// readAll returns the points and the number of invalid entries
+func readAll(t *testing.T, r Reader) ([]*storage.DataPoint, int) {
+	var res []*storage.DataPoint
+	var rejected int
+	for {
+		dp, err := r.Read()
+		if err == io.EOF {
+			return res, rejected
+		}
+		if _, ok := err.(*RowError); ok {
+			rejected++
+			continue
+		}
+		require.NoError(t, err)
+		res = append(res, dp)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	in := `device_id,time,lat,lng,temperature_2
+dev1,2019-11-22T14:28:00Z,48.8,2.2,21.5
+dev1,2019-11-22T14:29:00Z,not a lat,2.2,21.5
+,1574433000,48.9,2.3,
+dev2,2019-11-22T14:30:00Z,98.8,2.2,21.5
+`
+	r, err := NewReader("csv", strings.NewReader(in), "default")
+	require.NoError(t, err)
+	dps, rejected := readAll(t, r)
+	require.Equal(t, 2, rejected)
+	require.Len(t, dps, 2)
+	require.Equal(t, "dev1", dps[0].Key)
+	require.Equal(t, time.Date(2019, 11, 22, 14, 28, 0, 0, time.UTC), dps[0].Time)
+	require.Equal(t, "default", dps[1].Key)
+	require.Equal(t, 48.9, dps[1].Lat)
+	require.Equal(t, time.Unix(1574433000, 0).UTC(), dps[1].Time)
+
+	_, err = NewReader("csv", strings.NewReader("device_id,lat\n"), "")
+	require.Error(t, err)
+}
+
+func TestGeoJSON(t *testing.T) {
+	in := `{"type":"FeatureCollection","features":[
+{"type":"Feature","geometry":{"type":"Point","coordinates":[2.2,48.8]},"properties":{"device_id":"dev1","ts":"2019-11-22T14:28:00Z"}},
+{"type":"Feature","geometry":{"type":"LineString","coordinates":[[2.2,48.8],[2.3,48.9]]},"properties":{}},
+{"type":"Feature","geometry":{"type":"Point","coordinates":[2.3,48.9]},"properties":{"time":"2019-11-22T14:29:00Z"}}
+]}`
+	r, err := NewReader("geojson", strings.NewReader(in), "default")
+	require.NoError(t, err)
+	dps, rejected := readAll(t, r)
+	require.Equal(t, 1, rejected)
+	require.Len(t, dps, 2)
+	require.Equal(t, "dev1", dps[0].Key)
+	require.Equal(t, 48.8, dps[0].Lat)
+	require.Equal(t, 2.2, dps[0].Lng)
+	require.Equal(t, "default", dps[1].Key)
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	ts := time.Date(2019, 11, 22, 14, 28, 0, 0, time.UTC)
+	points := []storage.DataPoint{
+		{Key: "dev1", Lat: 48.8, Lng: 2.2, Time: ts},
+		{Key: "dev1", Lat: 48.9, Lng: 2.3, Time: ts.Add(time.Minute)},
+	}
+
+	for _, format := range []string{"csv", "gpx"} {
+		var buf bytes.Buffer
+		enc, err := export.NewEncoder(format, &buf, "dev1", log.NewNopLogger())
+		require.NoError(t, err)
+		for i := range points {
+			require.NoError(t, enc.Encode(&points[i]))
+		}
+		require.NoError(t, enc.Close())
+
+		r, err := NewReader(format, &buf, "dev1")
+		require.NoError(t, err)
+		dps, rejected := readAll(t, r)
+		require.Equal(t, 0, rejected, format)
+		require.Len(t, dps, 2, format)
+		for i, dp := range dps {
+			require.Equal(t, points[i], *dp, format)
+		}
+	}
+}
+
+func TestFormatFromPath(t *testing.T) {
+	require.Equal(t, "geojson", FormatFromPath("track.json"))
+	require.Equal(t, "gpx", FormatFromPath("/tmp/track.GPX"))
+}
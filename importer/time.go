@@ -0,0 +1,14 @@
+package importer
+
+import (
+	"strconv"
+	"time"
+)
+
+// parseTime accepts RFC3339 times or unix timestamps in seconds
+func parseTime(s string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
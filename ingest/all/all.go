@@ -0,0 +1,11 @@
+// Package all registers all the built-in ingestion sources
+package all
+
+import (
+	// sources registering their driver
+	_ "github.com/akhenakh/geottn/ingest/chirpstack"
+	_ "github.com/akhenakh/geottn/ingest/semtech"
+	_ "github.com/akhenakh/geottn/ingest/ttnv2"
+	_ "github.com/akhenakh/geottn/ingest/ttnv3"
+	_ "github.com/akhenakh/geottn/ingest/webhook"
+)
@@ -0,0 +1,161 @@
+// Package chirpstack decodes the uplink events of the ChirpStack network server
+package chirpstack
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+// event is the subset of the ChirpStack v3 (JSON and legacy JSON marshalers)
+// and v4 uplink events used
+type event struct {
+	// v3
+	ApplicationID   json.RawMessage `json:"applicationID"`
+	ApplicationName string          `json:"applicationName"`
+	DeviceName      string          `json:"deviceName"`
+	DevEUI          string          `json:"devEUI"`
+
+	// v4
+	DeviceInfo *struct {
+		ApplicationID   string `json:"applicationId"`
+		ApplicationName string `json:"applicationName"`
+		DeviceName      string `json:"deviceName"`
+		DevEUI          string `json:"devEui"`
+	} `json:"deviceInfo"`
+	Time *time.Time `json:"time"`
+
+	FCnt   uint32                 `json:"fCnt"`
+	Data   []byte                 `json:"data"`
+	Object map[string]interface{} `json:"object"`
+
+	RxInfo []struct {
+		GatewayID   string     `json:"gatewayID"`
+		GatewayIDV4 string     `json:"gatewayId"`
+		Time        *time.Time `json:"time"`
+		RSSI        float32    `json:"rssi"`
+		LoRaSNR     float32    `json:"loRaSNR"`
+		SNR         float32    `json:"snr"`
+		Location    *struct {
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+			Altitude  float64 `json:"altitude"`
+		} `json:"location"`
+	} `json:"rxInfo"`
+
+	TxInfo struct {
+		// in Hz
+		Frequency uint64 `json:"frequency"`
+
+		// v3, bandwidth in kHz
+		LoRaModulationInfo *struct {
+			Bandwidth       uint32 `json:"bandwidth"`
+			SpreadingFactor uint32 `json:"spreadingFactor"`
+		} `json:"loRaModulationInfo"`
+
+		// a string in v3, a modulation in v4
+		Modulation json.RawMessage `json:"modulation"`
+	} `json:"txInfo"`
+}
+
+// modulation is the v4 transmission modulation, bandwidth in Hz
+type modulation struct {
+	LoRa *struct {
+		Bandwidth       uint32 `json:"bandwidth"`
+		SpreadingFactor uint32 `json:"spreadingFactor"`
+	} `json:"lora"`
+}
+
+// Decode parses a ChirpStack uplink event, the device ID is its DevEUI in hexadecimal
+func Decode(b []byte) (*ingest.Uplink, error) {
+	var e event
+	if err := json.Unmarshal(b, &e); err != nil {
+		return nil, err
+	}
+
+	u := &ingest.Uplink{
+		FCnt:      e.FCnt,
+		Payload:   e.Data,
+		Fields:    e.Object,
+		Frequency: float32(float64(e.TxInfo.Frequency) / 1e6),
+	}
+
+	devEUI := e.DevEUI
+	if e.DeviceInfo != nil {
+		devEUI = e.DeviceInfo.DevEUI
+		u.AppID = e.DeviceInfo.ApplicationID
+	} else {
+		// a string with the JSON marshaler, a number with the legacy one
+		u.AppID = strings.Trim(string(e.ApplicationID), `"`)
+	}
+	if devEUI == "" {
+		return nil, ingest.ErrNoUplink
+	}
+	var err error
+	u.DevID, err = eui(devEUI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid devEUI: %w", err)
+	}
+	if e.Time != nil {
+		u.Time = *e.Time
+	}
+
+	var mod modulation
+	if len(e.TxInfo.Modulation) > 0 && e.TxInfo.Modulation[0] == '{' {
+		if err := json.Unmarshal(e.TxInfo.Modulation, &mod); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case e.TxInfo.LoRaModulationInfo != nil:
+		m := e.TxInfo.LoRaModulationInfo
+		u.DataRate = fmt.Sprintf("SF%dBW%d", m.SpreadingFactor, m.Bandwidth)
+	case mod.LoRa != nil:
+		u.DataRate = fmt.Sprintf("SF%dBW%d", mod.LoRa.SpreadingFactor, mod.LoRa.Bandwidth/1000)
+	}
+
+	for _, rx := range e.RxInfo {
+		id, snr := rx.GatewayID, rx.LoRaSNR
+		if rx.GatewayIDV4 != "" {
+			id, snr = rx.GatewayIDV4, rx.SNR
+		}
+		gw := ingest.Gateway{RSSI: rx.RSSI, SNR: snr}
+		if gw.ID, err = eui(id); err != nil {
+			return nil, fmt.Errorf("invalid gatewayID: %w", err)
+		}
+		if rx.Time != nil {
+			gw.Time = *rx.Time
+		}
+		if rx.Location != nil {
+			gw.Lat = rx.Location.Latitude
+			gw.Lng = rx.Location.Longitude
+			gw.Alt = int32(rx.Location.Altitude)
+		}
+		u.Gateways = append(u.Gateways, gw)
+	}
+	return u, nil
+}
+
+// eui returns an EUI in lower case hexadecimal,
+// ChirpStack v3 JSON marshaler is encoding them in base64, the legacy one in hexadecimal
+func eui(s string) (string, error) {
+	if len(s) == 16 {
+		if _, err := hex.DecodeString(s); err == nil {
+			return strings.ToLower(s), nil
+		}
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", err
+	}
+	if len(b) != 8 {
+		return "", errors.New("not an EUI")
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,75 @@
+package chirpstack
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+	}{
+		{"v3 json", `{
+			"applicationID": "12", "applicationName": "trackers", "deviceName": "tracker1",
+			"devEUI": "cHBwcHBwcAE=",
+			"rxInfo": [{"gatewayID": "uCfr//4AAAE=", "time": "2020-11-22T10:00:01Z", "rssi": -110, "loRaSNR": -2.5,
+				"location": {"latitude": 48.85, "longitude": 2.35, "altitude": 35}}],
+			"txInfo": {"frequency": 868100000, "modulation": "LORA", "loRaModulationInfo": {"bandwidth": 125, "spreadingFactor": 7}},
+			"fCnt": 42, "fPort": 1, "data": "AYgHqSAA1tgAAAA=",
+			"object": {"gpsLocation": {"1": {"latitude": 50.2048, "longitude": 5.5}}}
+		}`},
+		{"v3 legacy json", `{
+			"applicationID": 12, "applicationName": "trackers", "deviceName": "tracker1",
+			"devEUI": "7070707070707001",
+			"rxInfo": [{"gatewayID": "b827ebfffe000001", "time": "2020-11-22T10:00:01Z", "rssi": -110, "loRaSNR": -2.5,
+				"location": {"latitude": 48.85, "longitude": 2.35, "altitude": 35}}],
+			"txInfo": {"frequency": 868100000, "dr": 5},
+			"fCnt": 42, "fPort": 1, "data": "AYgHqSAA1tgAAAA=",
+			"object": {"gpsLocation": {"1": {"latitude": 50.2048, "longitude": 5.5}}}
+		}`},
+		{"v4", `{
+			"deviceInfo": {"applicationId": "12", "applicationName": "trackers", "deviceName": "tracker1", "devEui": "7070707070707001"},
+			"time": "2020-11-22T10:00:01.5Z",
+			"rxInfo": [{"gatewayId": "b827ebfffe000001", "time": "2020-11-22T10:00:01Z", "rssi": -110, "snr": -2.5,
+				"location": {"latitude": 48.85, "longitude": 2.35, "altitude": 35}}],
+			"txInfo": {"frequency": 868100000, "modulation": {"lora": {"bandwidth": 125000, "spreadingFactor": 7}}},
+			"fCnt": 42, "fPort": 1, "data": "AYgHqSAA1tgAAAA=",
+			"object": {"gpsLocation": {"1": {"latitude": 50.2048, "longitude": 5.5}}}
+		}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := Decode([]byte(tt.msg))
+			require.NoError(t, err)
+			require.Equal(t, "12", u.AppID)
+			require.Equal(t, "7070707070707001", u.DevID)
+			require.Equal(t, uint32(42), u.FCnt)
+			require.Equal(t, []byte{1, 136, 7, 169, 32, 0, 214, 216, 0, 0, 0}, u.Payload)
+			require.Contains(t, u.Fields, "gpsLocation")
+			require.InDelta(t, 868.1, u.Frequency, 0.0001)
+			require.Len(t, u.Gateways, 1)
+			gw := u.Gateways[0]
+			require.Equal(t, "b827ebfffe000001", gw.ID)
+			require.Equal(t, time.Date(2020, 11, 22, 10, 0, 1, 0, time.UTC), gw.Time)
+			require.Equal(t, float32(-110), gw.RSSI)
+			require.Equal(t, float32(-2.5), gw.SNR)
+			require.Equal(t, 48.85, gw.Lat)
+			require.Equal(t, int32(35), gw.Alt)
+			if tt.name != "v3 legacy json" {
+				require.Equal(t, "SF7BW125", u.DataRate)
+			}
+		})
+	}
+
+	_, err := Decode([]byte(`{"applicationID": "12", "type": "join"}`))
+	require.Equal(t, ingest.ErrNoUplink, err)
+
+	_, err = Decode([]byte(`{"devEUI": "notaneui"}`))
+	require.Error(t, err)
+}
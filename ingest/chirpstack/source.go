@@ -0,0 +1,56 @@
+package chirpstack
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/namsral/flag"
+
+	"github.com/akhenakh/geottn/ingest"
+	"github.com/akhenakh/geottn/ingest/mqtt"
+)
+
+// Name is the source name in the ingest registry
+const Name = "chirpstack"
+
+// DefaultTopic receives the uplinks of all the devices of all the applications
+const DefaultTopic = "application/+/device/+/event/up"
+
+func init() {
+	ingest.Register(Name, &driver{})
+}
+
+type driver struct {
+	broker   string
+	username string
+	password string
+	topic    string
+}
+
+// Flags implements ingest.Driver
+func (d *driver) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&d.broker, "chirpstackBroker", "", "the ChirpStack MQTT broker, eg: tcp://localhost:1883")
+	fs.StringVar(&d.username, "chirpstackUsername", "", "the ChirpStack MQTT username")
+	fs.StringVar(&d.password, "chirpstackPassword", "", "the ChirpStack MQTT password")
+	fs.StringVar(&d.topic, "chirpstackTopic", DefaultTopic, "the ChirpStack uplink topic")
+}
+
+// Open implements ingest.Driver
+func (d *driver) Open(logger log.Logger) (ingest.Source, error) {
+	if d.broker == "" {
+		return nil, ingest.ErrNotConfigured
+	}
+	cfg := mqtt.Config{
+		Broker:   d.broker,
+		Username: d.username,
+		Password: d.password,
+		ClientID: "geottnd-" + Name,
+	}
+	return mqtt.NewDialSource(logger, Name, cfg, d.topic, Decode), nil
+}
+
+// NewSource returns a source receiving the uplink events published on topic, DefaultTopic if empty
+func NewSource(logger log.Logger, client mqtt.Subscriber, topic string) *mqtt.Source {
+	if topic == "" {
+		topic = DefaultTopic
+	}
+	return mqtt.NewSource(logger, Name, client, topic, Decode)
+}
@@ -0,0 +1,47 @@
+package chirpstack
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/ingest"
+	"github.com/akhenakh/geottn/ingest/mqtt/mqtttest"
+)
+
+func TestSource(t *testing.T) {
+	b := mqtttest.NewBroker()
+	s := NewSource(log.NewNopLogger(), b, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	uplinks := make(chan *ingest.Uplink, 10)
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx, func(ctx context.Context, u *ingest.Uplink) {
+			uplinks <- u
+		})
+	}()
+	require.Eventually(t, func() bool {
+		return b.Subscriptions() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	b.Publish("application/12/device/7070707070707001/event/join", []byte(`{"devEUI": "cHBwcHBwcAE="}`))
+	b.Publish("application/12/device/7070707070707001/event/up", []byte(`{"devEUI": "invalid"}`))
+	b.Publish("application/12/device/7070707070707001/event/up", []byte(`{
+		"applicationID": "12", "devEUI": "cHBwcHBwcAE=", "fCnt": 42, "data": "AYgHqSAA1tgAAAA=",
+		"rxInfo": [{"gatewayID": "uCfr//4AAAE=", "rssi": -110, "loRaSNR": -2.5}],
+		"txInfo": {"frequency": 868100000}
+	}`))
+
+	u := <-uplinks
+	require.Equal(t, "7070707070707001", u.DevID)
+	require.Equal(t, uint32(42), u.FCnt)
+	require.Len(t, uplinks, 0)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.Equal(t, 0, b.Subscriptions())
+}
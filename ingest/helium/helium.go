@@ -0,0 +1,80 @@
+// Package helium decodes the uplinks of the Helium console HTTP integration
+package helium
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+// message is the subset of the Helium uplink message used
+type message struct {
+	Type       string `json:"type"`
+	AppEUI     string `json:"app_eui"`
+	DevEUI     string `json:"dev_eui"`
+	FCnt       uint32 `json:"fcnt"`
+	Payload    []byte `json:"payload"`
+	ReportedAt int64  `json:"reported_at"`
+	Decoded    *struct {
+		Payload map[string]interface{} `json:"payload"`
+	} `json:"decoded"`
+	Hotspots []struct {
+		ID         string  `json:"id"`
+		ReportedAt int64   `json:"reported_at"`
+		Frequency  float32 `json:"frequency"`
+		Spreading  string  `json:"spreading"`
+		RSSI       float32 `json:"rssi"`
+		SNR        float32 `json:"snr"`
+		Lat        float64 `json:"lat"`
+		Long       float64 `json:"long"`
+	} `json:"hotspots"`
+}
+
+// Decode parses a Helium uplink, the device ID is its DevEUI in hexadecimal
+func Decode(b []byte) (*ingest.Uplink, error) {
+	var msg message
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return nil, err
+	}
+	// older messages have no type
+	if msg.DevEUI == "" || (msg.Type != "" && msg.Type != "uplink") {
+		return nil, ingest.ErrNoUplink
+	}
+
+	u := &ingest.Uplink{
+		AppID:   strings.ToLower(msg.AppEUI),
+		DevID:   strings.ToLower(msg.DevEUI),
+		FCnt:    msg.FCnt,
+		Payload: msg.Payload,
+		Time:    millis(msg.ReportedAt),
+	}
+	if msg.Decoded != nil {
+		u.Fields = msg.Decoded.Payload
+	}
+
+	for _, h := range msg.Hotspots {
+		u.Gateways = append(u.Gateways, ingest.Gateway{
+			ID:   h.ID,
+			Time: millis(h.ReportedAt),
+			RSSI: h.RSSI,
+			SNR:  h.SNR,
+			Lat:  h.Lat,
+			Lng:  h.Long,
+		})
+		if u.DataRate == "" {
+			u.DataRate = h.Spreading
+			u.Frequency = h.Frequency
+		}
+	}
+	return u, nil
+}
+
+// millis returns the time of a unix timestamp in milliseconds, zero if unset
+func millis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond)).UTC()
+}
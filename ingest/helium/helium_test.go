@@ -0,0 +1,39 @@
+package helium
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+func TestDecode(t *testing.T) {
+	msg := `{
+		"type": "uplink", "app_eui": "70B3D57ED0000000", "dev_eui": "7070707070707001", "devaddr": "00000048",
+		"fcnt": 42, "port": 1, "payload": "AYgHqSAA1tgAAAA=", "reported_at": 1606039201500,
+		"decoded": {"payload": {"latitude": 50.2048, "longitude": 5.5}, "status": "success"},
+		"hotspots": [{
+			"id": "112MWdscG3DjHTxdCrtuLk", "name": "cool-hotspot-name", "reported_at": 1606039201000,
+			"frequency": 868.1, "spreading": "SF7BW125", "rssi": -110, "snr": -2.5, "lat": 48.85, "long": 2.35, "status": "success"
+		}]
+	}`
+	u, err := Decode([]byte(msg))
+	require.NoError(t, err)
+	require.Equal(t, "70b3d57ed0000000", u.AppID)
+	require.Equal(t, "7070707070707001", u.DevID)
+	require.Equal(t, uint32(42), u.FCnt)
+	require.Equal(t, []byte{1, 136, 7, 169, 32, 0, 214, 216, 0, 0, 0}, u.Payload)
+	require.Equal(t, 50.2048, u.Fields["latitude"])
+	require.Equal(t, time.Date(2020, 11, 22, 10, 0, 1, 5e8, time.UTC), u.Time)
+	require.Equal(t, "SF7BW125", u.DataRate)
+	require.Equal(t, float32(868.1), u.Frequency)
+	require.Len(t, u.Gateways, 1)
+	require.Equal(t, "112MWdscG3DjHTxdCrtuLk", u.Gateways[0].ID)
+	require.Equal(t, time.Date(2020, 11, 22, 10, 0, 1, 0, time.UTC), u.Gateways[0].Time)
+	require.Equal(t, 2.35, u.Gateways[0].Lng)
+
+	_, err = Decode([]byte(`{"type": "join", "dev_eui": "7070707070707001"}`))
+	require.Equal(t, ingest.ErrNoUplink, err)
+}
@@ -0,0 +1,59 @@
+// Package ingest normalizes the uplinks received from the network servers
+package ingest
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNoUplink is returned when decoding a network server message that is not an uplink,
+// eg: a join or a status event
+var ErrNoUplink = errors.New("not an uplink message")
+
+// Uplink is an uplink message normalized from the network server format
+type Uplink struct {
+	// AppID and DevID are the application and device IDs on the network server
+	AppID string
+	DevID string
+
+	FCnt uint32
+
+	// Payload is the raw application payload
+	Payload []byte
+
+	// Fields are the values decoded by the network server if any
+	Fields map[string]interface{}
+
+	// Time is the reception time by the network server, zero if unknown
+	Time time.Time
+
+	// DataRate is the LoRa data rate, eg SF7BW125
+	DataRate string
+
+	// Frequency in MHz
+	Frequency float32
+
+	// Gateways receiving the uplink
+	Gateways []Gateway
+}
+
+// Gateway is the reception of an uplink by a gateway
+type Gateway struct {
+	ID string
+
+	// Time is the reception time by the gateway, zero if unknown
+	Time time.Time
+
+	// RSSI in dBm, SNR in dB
+	RSSI float32
+	SNR  float32
+
+	// gateway location if known
+	Lat float64
+	Lng float64
+	Alt int32
+}
+
+// Handler is called for every received uplink
+type Handler func(ctx context.Context, u *Uplink)
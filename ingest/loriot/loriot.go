@@ -0,0 +1,86 @@
+// Package loriot decodes the uplinks of the LORIOT network server
+package loriot
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+// message is the subset of the LORIOT rx and gw messages used
+type message struct {
+	Cmd  string  `json:"cmd"`
+	EUI  string  `json:"EUI"`
+	TS   int64   `json:"ts"`
+	FCnt uint32  `json:"fcnt"`
+	Freq uint64  `json:"freq"`
+	RSSI float32 `json:"rssi"`
+	SNR  float32 `json:"snr"`
+	// eg: SF12 BW125 4/5
+	DR   string `json:"dr"`
+	Data string `json:"data"`
+
+	// gw messages only
+	Gws []struct {
+		GwEUI string     `json:"gweui"`
+		Time  *time.Time `json:"time"`
+		RSSI  float32    `json:"rssi"`
+		SNR   float32    `json:"snr"`
+		Lat   float64    `json:"lat"`
+		Lon   float64    `json:"lon"`
+		Alt   float64    `json:"alt"`
+	} `json:"gws"`
+}
+
+// Decode parses a LORIOT rx or gw uplink message, the device ID is its EUI in hexadecimal
+func Decode(b []byte) (*ingest.Uplink, error) {
+	var msg message
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return nil, err
+	}
+	if (msg.Cmd != "rx" && msg.Cmd != "gw") || msg.EUI == "" {
+		return nil, ingest.ErrNoUplink
+	}
+
+	payload, err := hex.DecodeString(msg.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %w", err)
+	}
+
+	u := &ingest.Uplink{
+		DevID:     strings.ToLower(msg.EUI),
+		FCnt:      msg.FCnt,
+		Payload:   payload,
+		Frequency: float32(float64(msg.Freq) / 1e6),
+	}
+	if msg.TS != 0 {
+		u.Time = time.Unix(0, msg.TS*int64(time.Millisecond)).UTC()
+	}
+	if dr := strings.Fields(msg.DR); len(dr) >= 2 {
+		u.DataRate = dr[0] + dr[1]
+	}
+
+	// rx messages only have the best reception
+	if len(msg.Gws) == 0 {
+		u.Gateways = []ingest.Gateway{{RSSI: msg.RSSI, SNR: msg.SNR}}
+	}
+	for _, gw := range msg.Gws {
+		g := ingest.Gateway{
+			ID:   strings.ToLower(gw.GwEUI),
+			RSSI: gw.RSSI,
+			SNR:  gw.SNR,
+			Lat:  gw.Lat,
+			Lng:  gw.Lon,
+			Alt:  int32(gw.Alt),
+		}
+		if gw.Time != nil {
+			g.Time = *gw.Time
+		}
+		u.Gateways = append(u.Gateways, g)
+	}
+	return u, nil
+}
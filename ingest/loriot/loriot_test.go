@@ -0,0 +1,43 @@
+package loriot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+func TestDecode(t *testing.T) {
+	u, err := Decode([]byte(`{
+		"cmd": "gw", "seqno": 1, "EUI": "7070707070707001", "ts": 1606039201500, "fcnt": 42, "port": 1,
+		"freq": 868100000, "rssi": -110, "snr": -2.5, "dr": "SF7 BW125 4/5", "ack": false,
+		"data": "018807a92000d6d8000000",
+		"gws": [{"rssi": -110, "snr": -2.5, "ts": 1606039201000, "time": "2020-11-22T10:00:01Z",
+			"gweui": "B827EBFFFE000001", "lat": 48.85, "lon": 2.35, "alt": 35}]
+	}`))
+	require.NoError(t, err)
+	require.Equal(t, "7070707070707001", u.DevID)
+	require.Equal(t, uint32(42), u.FCnt)
+	require.Equal(t, []byte{1, 136, 7, 169, 32, 0, 214, 216, 0, 0, 0}, u.Payload)
+	require.Equal(t, time.Date(2020, 11, 22, 10, 0, 1, 5e8, time.UTC), u.Time)
+	require.Equal(t, "SF7BW125", u.DataRate)
+	require.InDelta(t, 868.1, u.Frequency, 0.0001)
+	require.Len(t, u.Gateways, 1)
+	require.Equal(t, "b827ebfffe000001", u.Gateways[0].ID)
+	require.Equal(t, time.Date(2020, 11, 22, 10, 0, 1, 0, time.UTC), u.Gateways[0].Time)
+	require.Equal(t, 2.35, u.Gateways[0].Lng)
+
+	// rx messages have no gateway details
+	u, err = Decode([]byte(`{"cmd": "rx", "EUI": "7070707070707001", "rssi": -110, "snr": -2.5, "data": ""}`))
+	require.NoError(t, err)
+	require.Len(t, u.Gateways, 1)
+	require.Equal(t, float32(-110), u.Gateways[0].RSSI)
+
+	_, err = Decode([]byte(`{"cmd": "rx", "EUI": "7070707070707001", "data": "zz"}`))
+	require.Error(t, err)
+
+	_, err = Decode([]byte(`{"cmd": "txd", "EUI": "7070707070707001"}`))
+	require.Equal(t, ingest.ErrNoUplink, err)
+}
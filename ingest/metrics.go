@@ -0,0 +1,26 @@
+package ingest
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	UplinkCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "geottn",
+			Name:      "ingest_uplinks_total",
+			Help:      "The total number of uplinks received by source",
+		},
+		[]string{"source"},
+	)
+
+	ErrorCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "geottn",
+			Name:      "ingest_errors_total",
+			Help:      "The total number of invalid messages received by source",
+		},
+		[]string{"source"},
+	)
+)
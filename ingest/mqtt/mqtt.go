@@ -0,0 +1,113 @@
+// Package mqtt connects the ingestion sources to the MQTT brokers of the network servers
+package mqtt
+
+import (
+	"crypto/tls"
+	"strings"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+// Handler is called for every message received on a subscribed topic
+type Handler func(topic string, payload []byte)
+
+// Subscriber is the subset of an MQTT client used by the sources,
+// implemented by Client and by in memory brokers in tests
+type Subscriber interface {
+	Subscribe(topic string, h Handler) error
+	Unsubscribe(topic string) error
+}
+
+// Config is an MQTT broker connection
+type Config struct {
+	// Broker is the broker URI, eg: tls://eu1.cloud.thethings.network:8883
+	Broker string
+
+	Username string
+	Password string
+	ClientID string
+}
+
+// Client is a Subscriber connected to a broker, subscriptions are restored on reconnection
+type Client struct {
+	c paho.Client
+
+	mu   sync.Mutex
+	subs map[string]Handler
+}
+
+const (
+	// qos is at least once, the storage is idempotent for a device and a time
+	qos = 1
+
+	connectTimeout = 30 * time.Second
+)
+
+// Dial connects to the broker
+func Dial(cfg Config) (*Client, error) {
+	c := &Client{subs: make(map[string]Handler)}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetClientID(cfg.ClientID).
+		SetConnectTimeout(connectTimeout).
+		SetAutoReconnect(true).
+		SetOnConnectHandler(c.resubscribe)
+	if strings.HasPrefix(cfg.Broker, "tls://") || strings.HasPrefix(cfg.Broker, "ssl://") {
+		opts.SetTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+
+	c.c = paho.NewClient(opts)
+	token := c.c.Connect()
+	token.Wait()
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Subscribe implements Subscriber
+func (c *Client) Subscribe(topic string, h Handler) error {
+	c.mu.Lock()
+	c.subs[topic] = h
+	c.mu.Unlock()
+
+	return c.subscribe(topic, h)
+}
+
+// Unsubscribe implements Subscriber
+func (c *Client) Unsubscribe(topic string) error {
+	c.mu.Lock()
+	delete(c.subs, topic)
+	c.mu.Unlock()
+
+	token := c.c.Unsubscribe(topic)
+	token.Wait()
+	return token.Error()
+}
+
+// Close disconnects from the broker
+func (c *Client) Close() {
+	c.c.Disconnect(250)
+}
+
+func (c *Client) subscribe(topic string, h Handler) error {
+	token := c.c.Subscribe(topic, qos, func(_ paho.Client, m paho.Message) {
+		h(m.Topic(), m.Payload())
+	})
+	token.Wait()
+	return token.Error()
+}
+
+// resubscribe restores the subscriptions after a reconnection
+func (c *Client) resubscribe(paho.Client) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for topic, h := range c.subs {
+		_ = c.subscribe(topic, h)
+	}
+}
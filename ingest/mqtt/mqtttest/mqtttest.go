@@ -0,0 +1,68 @@
+// Package mqtttest provides an in memory MQTT broker stand-in to test the sources
+package mqtttest
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/akhenakh/geottn/ingest/mqtt"
+)
+
+// Broker is an mqtt.Subscriber delivering the published messages synchronously
+type Broker struct {
+	mu   sync.Mutex
+	subs map[string]mqtt.Handler
+}
+
+// NewBroker returns an empty broker
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[string]mqtt.Handler)}
+}
+
+// Subscribe implements mqtt.Subscriber
+func (b *Broker) Subscribe(topic string, h mqtt.Handler) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[topic] = h
+	return nil
+}
+
+// Unsubscribe implements mqtt.Subscriber
+func (b *Broker) Unsubscribe(topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, topic)
+	return nil
+}
+
+// Subscriptions returns the number of subscribed topics
+func (b *Broker) Subscriptions() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Publish delivers payload to the subscriptions matching topic
+func (b *Broker) Publish(topic string, payload []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for filter, h := range b.subs {
+		if Match(filter, topic) {
+			h(topic, payload)
+		}
+	}
+}
+
+// Match reports whether topic matches the subscription filter, with + and # wildcards
+func Match(filter, topic string) bool {
+	fs, ts := strings.Split(filter, "/"), strings.Split(topic, "/")
+	for i, f := range fs {
+		if f == "#" {
+			return true
+		}
+		if i >= len(ts) || (f != "+" && f != ts[i]) {
+			return false
+		}
+	}
+	return len(fs) == len(ts)
+}
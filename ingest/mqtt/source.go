@@ -0,0 +1,92 @@
+package mqtt
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+// Decoder parses an uplink message published by a network server
+type Decoder func(payload []byte) (*ingest.Uplink, error)
+
+// Source receives the uplinks published on a topic
+type Source struct {
+	logger log.Logger
+	name   string
+	client Subscriber
+	topic  string
+	decode Decoder
+}
+
+// NewSource returns a source receiving the uplinks published on topic,
+// name is the source name used in the metrics
+func NewSource(logger log.Logger, name string, client Subscriber, topic string, decode Decoder) *Source {
+	return &Source{
+		logger: logger,
+		name:   name,
+		client: client,
+		topic:  topic,
+		decode: decode,
+	}
+}
+
+// Run calls h for every uplink until ctx is done
+func (s *Source) Run(ctx context.Context, h ingest.Handler) error {
+	err := s.client.Subscribe(s.topic, func(topic string, payload []byte) {
+		u, err := s.decode(payload)
+		if err == ingest.ErrNoUplink {
+			return
+		}
+		if err != nil {
+			level.Warn(s.logger).Log("msg", "can't decode uplink", "topic", topic, "error", err)
+			ingest.ErrorCounter.WithLabelValues(s.name).Inc()
+			return
+		}
+		h(ctx, u)
+	})
+	if err != nil {
+		level.Error(s.logger).Log("msg", "can't subscribe to uplinks", "topic", s.topic, "error", err)
+		return err
+	}
+	level.Info(s.logger).Log("msg", "subscribed to uplink messages", "topic", s.topic)
+
+	<-ctx.Done()
+
+	level.Info(s.logger).Log("msg", "unsubscribing to uplink messages")
+	return s.client.Unsubscribe(s.topic)
+}
+
+// DialSource is a Source connecting to the broker when run
+type DialSource struct {
+	logger log.Logger
+	name   string
+	cfg    Config
+	topic  string
+	decode Decoder
+}
+
+// NewDialSource returns a source connecting to the broker cfg and receiving the uplinks published on topic
+func NewDialSource(logger log.Logger, name string, cfg Config, topic string, decode Decoder) *DialSource {
+	return &DialSource{
+		logger: logger,
+		name:   name,
+		cfg:    cfg,
+		topic:  topic,
+		decode: decode,
+	}
+}
+
+// Run implements ingest.Source
+func (s *DialSource) Run(ctx context.Context, h ingest.Handler) error {
+	client, err := Dial(s.cfg)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "can't connect to the MQTT broker", "broker", s.cfg.Broker, "error", err)
+		return err
+	}
+	defer client.Close()
+
+	return NewSource(s.logger, s.name, client, s.topic, s.decode).Run(ctx, h)
+}
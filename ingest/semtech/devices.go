@@ -0,0 +1,68 @@
+package semtech
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Device is an ABP device of the local device table
+type Device struct {
+	// ID is the device ID used to store its data points
+	ID    string `json:"dev_id"`
+	AppID string `json:"app_id,omitempty"`
+
+	// DevAddr, NwkSKey and AppSKey in hexadecimal, msb first
+	DevAddr string `json:"dev_addr"`
+	NwkSKey string `json:"nwk_s_key"`
+	AppSKey string `json:"app_s_key"`
+}
+
+// LoadDevices reads a device table, a JSON array of Device
+func LoadDevices(path string) ([]Device, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var devices []Device
+	if err := json.Unmarshal(b, &devices); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// device is a Device with its decoded address and keys
+type device struct {
+	id      string
+	appID   string
+	devAddr uint32
+	nwkSKey []byte
+	appSKey []byte
+}
+
+func (d Device) parse() (*device, error) {
+	if d.ID == "" {
+		return nil, fmt.Errorf("device %s: missing dev_id", d.DevAddr)
+	}
+	addr, err := hex.DecodeString(d.DevAddr)
+	if err != nil || len(addr) != 4 {
+		return nil, fmt.Errorf("device %s: invalid dev_addr %q", d.ID, d.DevAddr)
+	}
+	nwkSKey, err := hex.DecodeString(d.NwkSKey)
+	if err != nil || len(nwkSKey) != 16 {
+		return nil, fmt.Errorf("device %s: invalid nwk_s_key", d.ID)
+	}
+	appSKey, err := hex.DecodeString(d.AppSKey)
+	if err != nil || len(appSKey) != 16 {
+		return nil, fmt.Errorf("device %s: invalid app_s_key", d.ID)
+	}
+	return &device{
+		id:      d.ID,
+		appID:   d.AppID,
+		devAddr: binary.BigEndian.Uint32(addr),
+		nwkSKey: nwkSKey,
+		appSKey: appSKey,
+	}, nil
+}
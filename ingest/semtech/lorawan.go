@@ -0,0 +1,175 @@
+package semtech
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// LoRaWAN 1.0 message types
+const (
+	mTypeUnconfirmedDataUp = 2
+	mTypeConfirmedDataUp   = 4
+)
+
+var (
+	errNotDataUp   = errors.New("not a data uplink")
+	errInvalidSize = errors.New("invalid PHYPayload size")
+	errInvalidMIC  = errors.New("invalid MIC")
+)
+
+// frame is a parsed LoRaWAN 1.0 data uplink
+type frame struct {
+	// raw is the PHYPayload without the MIC
+	raw []byte
+	mic []byte
+
+	devAddr uint32
+	fCnt16  uint16
+	fPort   *uint8
+
+	// frmPayload is still encrypted
+	frmPayload []byte
+}
+
+// parseFrame parses a data uplink PHYPayload
+func parseFrame(b []byte) (*frame, error) {
+	// MHDR + DevAddr + FCtrl + FCnt + MIC
+	if len(b) < 1+4+1+2+4 {
+		return nil, errInvalidSize
+	}
+	mType := b[0] >> 5
+	if mType != mTypeUnconfirmedDataUp && mType != mTypeConfirmedDataUp {
+		return nil, errNotDataUp
+	}
+
+	f := &frame{
+		raw:     b[:len(b)-4],
+		mic:     b[len(b)-4:],
+		devAddr: binary.LittleEndian.Uint32(b[1:5]),
+		fCnt16:  binary.LittleEndian.Uint16(b[6:8]),
+	}
+
+	fOptsLen := int(b[5] & 0x0f)
+	i := 8 + fOptsLen
+	if i > len(f.raw) {
+		return nil, errInvalidSize
+	}
+	if i < len(f.raw) {
+		port := f.raw[i]
+		f.fPort = &port
+		f.frmPayload = f.raw[i+1:]
+	}
+	return f, nil
+}
+
+// validMIC reports whether the frame MIC matches, for the full 32 bits frame counter fCnt
+func (f *frame) validMIC(nwkSKey []byte, fCnt uint32) (bool, error) {
+	b0 := make([]byte, 16, 16+len(f.raw))
+	b0[0] = 0x49
+	// direction uplink 0
+	binary.LittleEndian.PutUint32(b0[6:10], f.devAddr)
+	binary.LittleEndian.PutUint32(b0[10:14], fCnt)
+	b0[15] = byte(len(f.raw))
+
+	mac, err := cmac(nwkSKey, append(b0, f.raw...))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(mac[:4], f.mic) == 1, nil
+}
+
+// decrypt returns the clear FRMPayload, encrypted with the NwkSKey on port 0, with the AppSKey otherwise
+func (f *frame) decrypt(nwkSKey, appSKey []byte, fCnt uint32) ([]byte, error) {
+	key := appSKey
+	if f.fPort != nil && *f.fPort == 0 {
+		key = nwkSKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]byte, len(f.frmPayload))
+	a := make([]byte, 16)
+	s := make([]byte, 16)
+	for i := 0; i < len(res); i += 16 {
+		a[0] = 0x01
+		// direction uplink 0
+		binary.LittleEndian.PutUint32(a[6:10], f.devAddr)
+		binary.LittleEndian.PutUint32(a[10:14], fCnt)
+		a[15] = byte(i/16 + 1)
+		block.Encrypt(s, a)
+		for j := i; j < len(res) && j < i+16; j++ {
+			res[j] = f.frmPayload[j] ^ s[j-i]
+		}
+	}
+	return res, nil
+}
+
+// fullFCnt returns the 32 bits frame counter closest after last for the transmitted 16 bits fCnt16
+func fullFCnt(last uint32, fCnt16 uint16) uint32 {
+	fCnt := last&0xffff0000 | uint32(fCnt16)
+	if fCnt < last {
+		fCnt += 0x10000
+	}
+	return fCnt
+}
+
+// cmac returns the AES-CMAC of msg, RFC 4493
+func cmac(key, msg []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// subkeys
+	l := make([]byte, 16)
+	block.Encrypt(l, l)
+	k1 := shift(l)
+	k2 := shift(k1)
+
+	n := (len(msg) + 15) / 16
+	last := make([]byte, 16)
+	if n > 0 && len(msg)%16 == 0 {
+		copy(last, msg[(n-1)*16:])
+		xor(last, k1)
+	} else {
+		if n == 0 {
+			n = 1
+		}
+		rest := msg[(n-1)*16:]
+		copy(last, rest)
+		last[len(rest)] = 0x80
+		xor(last, k2)
+	}
+
+	x := make([]byte, 16)
+	mode := cipher.NewCBCEncrypter(block, x)
+	for i := 0; i < n-1; i++ {
+		mode.CryptBlocks(x, msg[i*16:(i+1)*16])
+	}
+	mode.CryptBlocks(x, last)
+	return x, nil
+}
+
+// shift returns b shifted left by one bit, xored with Rb if the msb was set
+func shift(b []byte) []byte {
+	res := make([]byte, len(b))
+	for i := 0; i < len(b)-1; i++ {
+		res[i] = b[i]<<1 | b[i+1]>>7
+	}
+	res[len(b)-1] = b[len(b)-1] << 1
+	if b[0]&0x80 != 0 {
+		res[len(b)-1] ^= 0x87
+	}
+	return res
+}
+
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,70 @@
+package semtech
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestCMAC(t *testing.T) {
+	// RFC 4493 test vectors
+	key := unhex(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	msg := unhex(t, "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+	tests := []struct {
+		len int
+		mac string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	}
+	for _, tt := range tests {
+		mac, err := cmac(key, msg[:tt.len])
+		require.NoError(t, err)
+		require.Equal(t, tt.mac, hex.EncodeToString(mac))
+	}
+}
+
+func TestFrame(t *testing.T) {
+	nwkSKey := unhex(t, "44024241ed4ce9a68c6a8bc055233fd3")
+	appSKey := unhex(t, "ec925802ae430ca77fd3dd73cb2cc588")
+
+	f, err := parseFrame(unhex(t, "40F17DBE4900020001954378762B11FF0D"))
+	require.NoError(t, err)
+	require.Equal(t, uint32(0x49be7df1), f.devAddr)
+	require.Equal(t, uint16(2), f.fCnt16)
+	require.Equal(t, uint8(1), *f.fPort)
+
+	ok, err := f.validMIC(nwkSKey, 2)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = f.validMIC(nwkSKey, 0x10002)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	payload, err := f.decrypt(nwkSKey, appSKey, 2)
+	require.NoError(t, err)
+	require.Equal(t, "test", string(payload))
+
+	// join request
+	_, err = parseFrame(unhex(t, "00DC0000D07ED5B3701E6FEDF57CEEAF0085CC587FE913"))
+	require.Equal(t, errNotDataUp, err)
+
+	_, err = parseFrame(unhex(t, "40F17DBE49"))
+	require.Equal(t, errInvalidSize, err)
+}
+
+func TestFullFCnt(t *testing.T) {
+	require.Equal(t, uint32(2), fullFCnt(0, 2))
+	require.Equal(t, uint32(0x10001), fullFCnt(0xfffe, 1))
+	require.Equal(t, uint32(0x1fffe), fullFCnt(0x1fff0, 0xfffe))
+}
@@ -0,0 +1,366 @@
+// Package semtech is a minimal LoRaWAN network server for ABP devices,
+// receiving the uplinks from gateways running the Semtech UDP packet forwarder
+package semtech
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/namsral/flag"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+// Name is the source name in the ingest registry
+const Name = "semtech"
+
+// packet forwarder protocol identifiers
+const (
+	pushData = 0x00
+	pushAck  = 0x01
+	pullData = 0x02
+	pullAck  = 0x04
+)
+
+const (
+	// dedupWindow is the time to wait for the other gateways receiving the same uplink
+	dedupWindow = 200 * time.Millisecond
+
+	maxPacketSize = 65535
+
+	// resetFrames is the number of consecutive frames counting up from a low counter confirming a device reset
+	resetFrames = 3
+)
+
+func init() {
+	ingest.Register(Name, &driver{})
+}
+
+type driver struct {
+	addr        string
+	devices     string
+	resetWindow uint
+}
+
+// Flags implements ingest.Driver
+func (d *driver) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&d.addr, "semtechAddr", "", "the UDP address receiving the Semtech packet forwarder traffic, eg: :1700, disabled if empty")
+	fs.StringVar(&d.devices, "semtechDevices", "devices.json", "the ABP device table of the Semtech packet forwarder source")
+	fs.UintVar(&d.resetWindow, "semtechResetWindow", 0, "a frame counter lower than the last one can start a device reset only below this value, 0 to refuse resets")
+}
+
+// Open implements ingest.Driver
+func (d *driver) Open(logger log.Logger) (ingest.Source, error) {
+	if d.addr == "" {
+		return nil, ingest.ErrNotConfigured
+	}
+	devices, err := LoadDevices(d.devices)
+	if err != nil {
+		return nil, err
+	}
+	if d.resetWindow > 0xffff {
+		return nil, errors.New("semtechResetWindow must be lower than 65536")
+	}
+	return NewSource(logger, d.addr, devices, uint16(d.resetWindow))
+}
+
+// Source receives the uplinks of the devices from the packet forwarders,
+// checks their MIC, decrypts their payload and merges the receptions of several gateways.
+// Frame counters must increase, except for ABP devices resetting them when restarting:
+// a counter lower than the last one and below resetWindow is a reset candidate,
+// the reset is accepted once resetFrames consecutive counters were received, other uplinks are dropped as replays.
+// The frames preceding the confirmation of a reset are dropped, a frame of the current session cancels it.
+// The last counters are kept in memory only, after a restart the first uplink of each device is accepted whatever its counter.
+type Source struct {
+	logger      log.Logger
+	addr        string
+	devices     map[uint32][]*device
+	resetWindow uint16
+
+	mu       sync.Mutex
+	fCnts    map[string]uint32
+	resets   map[string]resetCandidate
+	pending  map[string]*ingest.Uplink
+	gateways map[string]location
+}
+
+// resetCandidate is the last frame counter of a possible device reset
+type resetCandidate struct {
+	fCnt   uint32
+	frames int
+}
+
+// location is a gateway location reported in its status
+type location struct {
+	lat, lng float64
+	alt      int32
+}
+
+// rxpk is a received packet, as forwarded in PUSH_DATA
+type rxpk struct {
+	Time string  `json:"time"`
+	Freq float64 `json:"freq"`
+	// 1 for CRC ok
+	Stat int     `json:"stat"`
+	Datr string  `json:"datr"`
+	RSSI float32 `json:"rssi"`
+	LSNR float32 `json:"lsnr"`
+	Data string  `json:"data"`
+}
+
+// stat is a gateway status, as forwarded in PUSH_DATA
+type stat struct {
+	Lati float64 `json:"lati"`
+	Long float64 `json:"long"`
+	Alti int32   `json:"alti"`
+}
+
+// NewSource returns a source listening on the UDP addr for the ABP devices,
+// a frame counter lower than the last one can start a device reset only below resetWindow
+func NewSource(logger log.Logger, addr string, devices []Device, resetWindow uint16) (*Source, error) {
+	s := &Source{
+		logger:      logger,
+		addr:        addr,
+		resetWindow: resetWindow,
+		devices:     make(map[uint32][]*device),
+		fCnts:       make(map[string]uint32),
+		resets:      make(map[string]resetCandidate),
+		pending:     make(map[string]*ingest.Uplink),
+		gateways:    make(map[string]location),
+	}
+	for _, d := range devices {
+		dev, err := d.parse()
+		if err != nil {
+			return nil, err
+		}
+		s.devices[dev.devAddr] = append(s.devices[dev.devAddr], dev)
+	}
+	return s, nil
+}
+
+// Run implements ingest.Source
+func (s *Source) Run(ctx context.Context, h ingest.Handler) error {
+	conn, err := net.ListenPacket("udp", s.addr)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "can't listen for packet forwarders", "addr", s.addr, "error", err)
+		return err
+	}
+	level.Info(s.logger).Log("msg", "listening for packet forwarders", "addr", s.addr)
+	return s.Serve(ctx, conn, h)
+}
+
+// Serve receives the packet forwarders traffic on conn until ctx is done
+func (s *Source) Serve(ctx context.Context, conn net.PacketConn, h ingest.Handler) error {
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		s.handlePacket(ctx, conn, addr, buf[:n], h)
+	}
+}
+
+func (s *Source) handlePacket(ctx context.Context, conn net.PacketConn, addr net.Addr, b []byte, h ingest.Handler) {
+	// protocol version, random token, identifier, gateway EUI
+	if len(b) < 12 || (b[0] != 1 && b[0] != 2) {
+		return
+	}
+
+	switch b[3] {
+	case pushData:
+		if _, err := conn.WriteTo([]byte{b[0], b[1], b[2], pushAck}, addr); err != nil {
+			level.Warn(s.logger).Log("msg", "can't send PUSH_ACK", "addr", addr, "error", err)
+		}
+
+		var data struct {
+			Rxpk []rxpk `json:"rxpk"`
+			Stat *stat  `json:"stat"`
+		}
+		if err := json.Unmarshal(b[12:], &data); err != nil {
+			level.Warn(s.logger).Log("msg", "invalid PUSH_DATA", "addr", addr, "error", err)
+			ingest.ErrorCounter.WithLabelValues(Name).Inc()
+			return
+		}
+
+		eui := hex.EncodeToString(b[4:12])
+		if data.Stat != nil && (data.Stat.Lati != 0 || data.Stat.Long != 0) {
+			s.mu.Lock()
+			s.gateways[eui] = location{lat: data.Stat.Lati, lng: data.Stat.Long, alt: data.Stat.Alti}
+			s.mu.Unlock()
+		}
+		for _, rx := range data.Rxpk {
+			if err := s.handleRxpk(ctx, eui, &rx, h); err != nil {
+				level.Debug(s.logger).Log("msg", "dropping packet", "gateway", eui, "error", err)
+			}
+		}
+	case pullData:
+		if _, err := conn.WriteTo([]byte{b[0], b[1], b[2], pullAck}, addr); err != nil {
+			level.Warn(s.logger).Log("msg", "can't send PULL_ACK", "addr", addr, "error", err)
+		}
+	}
+}
+
+var (
+	errUnknownDevice = errors.New("unknown device or invalid MIC")
+	errReplayed      = errors.New("replayed uplink")
+	errResetPending  = errors.New("possible device reset, waiting for the next frames")
+)
+
+// handleRxpk verifies and decrypts a packet received by the gateway eui,
+// the uplink is passed to h once all the gateways had the time to forward it
+func (s *Source) handleRxpk(ctx context.Context, eui string, rx *rxpk, h ingest.Handler) error {
+	if rx.Stat != 1 {
+		return errors.New("invalid CRC")
+	}
+	b, err := base64.StdEncoding.DecodeString(rx.Data)
+	if err != nil {
+		return err
+	}
+	f, err := parseFrame(b)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dev, fCnt, err := s.device(f)
+	if err != nil {
+		ingest.ErrorCounter.WithLabelValues(Name).Inc()
+		return err
+	}
+
+	gw := ingest.Gateway{ID: eui, RSSI: rx.RSSI, SNR: rx.LSNR}
+	if t, err := time.Parse(time.RFC3339Nano, rx.Time); err == nil {
+		gw.Time = t.UTC()
+	}
+	if loc, ok := s.gateways[eui]; ok {
+		gw.Lat, gw.Lng, gw.Alt = loc.lat, loc.lng, loc.alt
+	}
+
+	key := pendingKey(dev.id, fCnt)
+	if u, ok := s.pending[key]; ok {
+		u.Gateways = append(u.Gateways, gw)
+		return nil
+	}
+	if last, ok := s.fCnts[dev.id]; ok && last == fCnt {
+		return errors.New("duplicated uplink")
+	}
+
+	payload, err := f.decrypt(dev.nwkSKey, dev.appSKey, fCnt)
+	if err != nil {
+		return err
+	}
+	u := &ingest.Uplink{
+		AppID:     dev.appID,
+		DevID:     dev.id,
+		FCnt:      fCnt,
+		Payload:   payload,
+		Time:      time.Now().UTC(),
+		DataRate:  rx.Datr,
+		Frequency: float32(rx.Freq),
+		Gateways:  []ingest.Gateway{gw},
+	}
+	s.pending[key] = u
+
+	time.AfterFunc(dedupWindow, func() {
+		s.mu.Lock()
+		delete(s.pending, key)
+		s.fCnts[dev.id] = fCnt
+		s.mu.Unlock()
+		h(ctx, u)
+	})
+	return nil
+}
+
+// device returns the device sending f and its full frame counter,
+// trying the counter following the last one then a reset counter below the reset window,
+// a frame sent with a lower counter is reported as replayed
+// must be called with s.mu held
+func (s *Source) device(f *frame) (*device, uint32, error) {
+	for _, dev := range s.devices[f.devAddr] {
+		last := s.fCnts[dev.id]
+		fCnt := fullFCnt(last, f.fCnt16)
+		ok, err := f.validMIC(dev.nwkSKey, fCnt)
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			delete(s.resets, dev.id)
+			return dev, fCnt, nil
+		}
+		if fCnt < 0x10000 {
+			continue
+		}
+
+		// a counter lower than the last one, a device reset or a replay
+		ok, err = f.validMIC(dev.nwkSKey, uint32(f.fCnt16))
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok && uint32(f.fCnt16) < last && f.fCnt16 < s.resetWindow {
+			return s.reset(dev, uint32(f.fCnt16))
+		}
+		if !ok && fCnt-0x10000 != uint32(f.fCnt16) {
+			ok, err = f.validMIC(dev.nwkSKey, fCnt-0x10000)
+			if err != nil {
+				return nil, 0, err
+			}
+		}
+		if ok {
+			return nil, 0, errReplayed
+		}
+	}
+	return nil, 0, errUnknownDevice
+}
+
+// reset records the frame counter fCnt of a possible reset of dev,
+// the device is returned once resetFrames consecutive counters were received
+// must be called with s.mu held
+func (s *Source) reset(dev *device, fCnt uint32) (*device, uint32, error) {
+	if _, ok := s.pending[pendingKey(dev.id, fCnt)]; ok {
+		// another gateway received the frame confirming the reset
+		return dev, fCnt, nil
+	}
+
+	c, ok := s.resets[dev.id]
+	switch {
+	case ok && fCnt == c.fCnt:
+		// another gateway received the same frame
+		return nil, 0, errResetPending
+	case ok && fCnt == c.fCnt+1:
+		c.fCnt = fCnt
+		c.frames++
+	default:
+		c = resetCandidate{fCnt: fCnt, frames: 1}
+	}
+	if c.frames < resetFrames {
+		s.resets[dev.id] = c
+		return nil, 0, errResetPending
+	}
+	delete(s.resets, dev.id)
+	return dev, fCnt, nil
+}
+
+// pendingKey is the key of an uplink waiting for the receptions of the other gateways
+func pendingKey(id string, fCnt uint32) string {
+	return id + "#" + strconv.FormatUint(uint64(fCnt), 10)
+}
@@ -0,0 +1,181 @@
+package semtech
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+func TestSource(t *testing.T) {
+	s, err := NewSource(log.NewNopLogger(), "", []Device{{
+		ID:      "tracker1",
+		AppID:   "trackers",
+		DevAddr: "49BE7DF1",
+		NwkSKey: "44024241ed4ce9a68c6a8bc055233fd3",
+		AppSKey: "ec925802ae430ca77fd3dd73cb2cc588",
+	}}, 16)
+	require.NoError(t, err)
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	uplinks := make(chan *ingest.Uplink, 10)
+	done := make(chan error)
+	go func() {
+		done <- s.Serve(ctx, conn, func(ctx context.Context, u *ingest.Uplink) {
+			uplinks <- u
+		})
+	}()
+
+	gw, err := net.Dial("udp", conn.LocalAddr().String())
+	require.NoError(t, err)
+	defer gw.Close()
+
+	send := func(id byte, eui string, json string) []byte {
+		pkt := append([]byte{2, 0xca, 0xfe, id}, unhex(t, eui)...)
+		_, err := gw.Write(append(pkt, json...))
+		require.NoError(t, err)
+
+		require.NoError(t, gw.SetReadDeadline(time.Now().Add(time.Second)))
+		ack := make([]byte, 16)
+		n, err := gw.Read(ack)
+		require.NoError(t, err)
+		return ack[:n]
+	}
+	rxpk := func(data, rssi string) string {
+		return fmt.Sprintf(`{"rxpk":[{"time":"2020-11-22T10:00:01.000001Z","tmst":3512348611,"chan":2,"rfch":0,
+			"freq":868.1,"stat":1,"modu":"LORA","datr":"SF7BW125","codr":"4/5","rssi":%s,"lsnr":5.5,"size":17,"data":"%s"}]}`,
+			rssi, data)
+	}
+	frame := base64.StdEncoding.EncodeToString(unhex(t, "40F17DBE4900020001954378762B11FF0D"))
+
+	require.Equal(t, []byte{2, 0xca, 0xfe, pullAck}, send(pullData, "b827ebfffe000001", ""))
+
+	// gateway status with its location
+	require.Equal(t, []byte{2, 0xca, 0xfe, pushAck},
+		send(pushData, "b827ebfffe000001", `{"stat":{"time":"2020-11-22 10:00:00 GMT","lati":48.85,"long":2.35,"alti":35}}`))
+
+	// same uplink received by 2 gateways
+	require.Equal(t, []byte{2, 0xca, 0xfe, pushAck}, send(pushData, "b827ebfffe000001", rxpk(frame, "-110")))
+	require.Equal(t, []byte{2, 0xca, 0xfe, pushAck}, send(pushData, "b827ebfffe000002", rxpk(frame, "-95")))
+
+	u := <-uplinks
+	require.Equal(t, "tracker1", u.DevID)
+	require.Equal(t, "trackers", u.AppID)
+	require.Equal(t, uint32(2), u.FCnt)
+	require.Equal(t, "test", string(u.Payload))
+	require.Equal(t, "SF7BW125", u.DataRate)
+	require.Len(t, u.Gateways, 2)
+	require.Equal(t, "b827ebfffe000001", u.Gateways[0].ID)
+	require.Equal(t, float32(-110), u.Gateways[0].RSSI)
+	require.Equal(t, 48.85, u.Gateways[0].Lat)
+	require.Equal(t, time.Date(2020, 11, 22, 10, 0, 1, 1000, time.UTC), u.Gateways[0].Time)
+	require.Equal(t, "b827ebfffe000002", u.Gateways[1].ID)
+	require.Equal(t, 0.0, u.Gateways[1].Lat)
+
+	// late duplicate, corrupted MIC, unknown device
+	send(pushData, "b827ebfffe000003", rxpk(frame, "-120"))
+	send(pushData, "b827ebfffe000001", rxpk(base64.StdEncoding.EncodeToString(unhex(t, "40F17DBE4900020001954378762B11FF0E")), "-110"))
+	send(pushData, "b827ebfffe000001", rxpk(base64.StdEncoding.EncodeToString(unhex(t, "40F17DBE4A00020001954378762B11FF0D")), "-110"))
+	time.Sleep(2 * dedupWindow)
+	require.Len(t, uplinks, 0)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+// encodeFrame returns an unconfirmed data uplink PHYPayload sent with the frame counter fCnt
+func encodeFrame(t *testing.T, devAddr, fCnt uint32, payload []byte, nwkSKey, appSKey []byte) string {
+	b := []byte{mTypeUnconfirmedDataUp << 5, 0, 0, 0, 0, 0, 0, 0, 1}
+	binary.LittleEndian.PutUint32(b[1:5], devAddr)
+	binary.LittleEndian.PutUint16(b[6:8], uint16(fCnt))
+	port := uint8(1)
+	f := &frame{devAddr: devAddr, fPort: &port, frmPayload: payload}
+	// the payload encryption is symmetric
+	enc, err := f.decrypt(nwkSKey, appSKey, fCnt)
+	require.NoError(t, err)
+	b = append(b, enc...)
+
+	b0 := make([]byte, 16)
+	b0[0] = 0x49
+	binary.LittleEndian.PutUint32(b0[6:10], devAddr)
+	binary.LittleEndian.PutUint32(b0[10:14], fCnt)
+	b0[15] = byte(len(b))
+	mac, err := cmac(nwkSKey, append(b0, b...))
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(append(b, mac[:4]...))
+}
+
+func TestSourceReplay(t *testing.T) {
+	nwkSKey := unhex(t, "44024241ed4ce9a68c6a8bc055233fd3")
+	appSKey := unhex(t, "ec925802ae430ca77fd3dd73cb2cc588")
+	s, err := NewSource(log.NewNopLogger(), "", []Device{{
+		ID:      "tracker1",
+		AppID:   "trackers",
+		DevAddr: "49BE7DF1",
+		NwkSKey: "44024241ed4ce9a68c6a8bc055233fd3",
+		AppSKey: "ec925802ae430ca77fd3dd73cb2cc588",
+	}}, 16)
+	require.NoError(t, err)
+
+	uplinks := make(chan *ingest.Uplink, 10)
+	h := func(ctx context.Context, u *ingest.Uplink) {
+		uplinks <- u
+	}
+	handle := func(fCnt uint32) error {
+		rx := &rxpk{Stat: 1, Data: encodeFrame(t, 0x49BE7DF1, fCnt, []byte("test"), nwkSKey, appSKey)}
+		err := s.handleRxpk(context.Background(), "b827ebfffe000001", rx, h)
+		time.Sleep(2 * dedupWindow)
+		return err
+	}
+
+	require.NoError(t, handle(100))
+	require.Equal(t, uint32(100), (<-uplinks).FCnt)
+	require.NoError(t, handle(0x10001))
+	require.Equal(t, uint32(0x10001), (<-uplinks).FCnt)
+
+	// replaying older uplinks
+	require.Equal(t, errReplayed, handle(100))
+	require.Equal(t, errReplayed, handle(0x10000))
+	require.Len(t, uplinks, 0)
+
+	// a replayed first frame neither lowers the counter nor allows replaying the older frames
+	require.Equal(t, errResetPending, handle(0))
+	require.Equal(t, errReplayed, handle(100))
+	require.Equal(t, errReplayed, handle(0x10000))
+	require.Equal(t, errResetPending, handle(5))
+	require.Len(t, uplinks, 0)
+	require.Equal(t, uint32(0x10001), s.fCnts["tracker1"])
+
+	// a frame of the current session cancels the reset
+	require.Equal(t, errResetPending, handle(6))
+	require.NoError(t, handle(0x10002))
+	require.Equal(t, uint32(0x10002), (<-uplinks).FCnt)
+	require.Equal(t, errResetPending, handle(7))
+	require.Len(t, uplinks, 0)
+
+	// the device restarted
+	require.Equal(t, errResetPending, handle(0))
+	require.Equal(t, errResetPending, handle(1))
+	require.NoError(t, handle(2))
+	u := <-uplinks
+	require.Equal(t, uint32(2), u.FCnt)
+	require.Equal(t, "test", string(u.Payload))
+	require.NoError(t, handle(3))
+	require.Equal(t, uint32(3), (<-uplinks).FCnt)
+
+	// no reset accepted
+	s.resetWindow = 0
+	require.Equal(t, errReplayed, handle(0))
+	require.Len(t, uplinks, 0)
+}
@@ -0,0 +1,109 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/namsral/flag"
+)
+
+// ErrNotConfigured is returned by Driver.Open when the source flags are not set
+var ErrNotConfigured = errors.New("source not configured")
+
+// Source yields the uplinks received from a network server,
+// sources implementing http.Handler are mounted on the web server under /ingest/{name}/
+type Source interface {
+	// Run calls h for every uplink until ctx is done
+	Run(ctx context.Context, h Handler) error
+}
+
+// Driver creates a Source, the source packages register their driver in their init
+type Driver interface {
+	// Flags registers the source configuration flags on fs
+	Flags(fs *flag.FlagSet)
+
+	// Open returns the source configured by its flags, ErrNotConfigured if they are not set
+	Open(logger log.Logger) (Source, error)
+}
+
+var (
+	mu      sync.RWMutex
+	drivers = make(map[string]Driver)
+)
+
+// Register makes a source driver available under name, it panics if name is already registered
+func Register(name string, d Driver) {
+	mu.Lock()
+	defer mu.Unlock()
+	if d == nil {
+		panic("ingest: Register driver is nil")
+	}
+	if _, dup := drivers[name]; dup {
+		panic("ingest: Register called twice for driver " + name)
+	}
+	drivers[name] = d
+}
+
+// Drivers returns the sorted names of the registered drivers
+func Drivers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	res := make([]string, 0, len(drivers))
+	for name := range drivers {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// RegisterFlags registers the flags of all the drivers on fs
+func RegisterFlags(fs *flag.FlagSet) {
+	for _, name := range Drivers() {
+		driver(name).Flags(fs)
+	}
+}
+
+// Open returns the sources names by name, if names is empty all the configured sources are returned
+func Open(logger log.Logger, names []string) (map[string]Source, error) {
+	all := len(names) == 0
+	if all {
+		names = Drivers()
+	}
+
+	res := make(map[string]Source)
+	for _, name := range names {
+		d := driver(name)
+		if d == nil {
+			return nil, fmt.Errorf("unknown source %q", name)
+		}
+
+		src, err := d.Open(log.With(logger, "source", name))
+		if err == ErrNotConfigured && all {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", name, err)
+		}
+		res[name] = src
+	}
+	return res, nil
+}
+
+func driver(name string) Driver {
+	mu.RLock()
+	defer mu.RUnlock()
+	return drivers[name]
+}
+
+// Instrument returns a handler counting the uplinks of the source name before calling h
+func Instrument(name string, h Handler) Handler {
+	c := UplinkCounter.WithLabelValues(name)
+	return func(ctx context.Context, u *Uplink) {
+		c.Inc()
+		h(ctx, u)
+	}
+}
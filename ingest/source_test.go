@@ -0,0 +1,57 @@
+package ingest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/namsral/flag"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type testDriver struct {
+	uplinks []*Uplink
+}
+
+func (d *testDriver) Flags(fs *flag.FlagSet) {}
+
+func (d *testDriver) Open(logger log.Logger) (Source, error) {
+	if len(d.uplinks) == 0 {
+		return nil, ErrNotConfigured
+	}
+	return d, nil
+}
+
+func (d *testDriver) Run(ctx context.Context, h Handler) error {
+	for _, u := range d.uplinks {
+		h(ctx, u)
+	}
+	return nil
+}
+
+func TestOpen(t *testing.T) {
+	Register("test1", &testDriver{uplinks: []*Uplink{{DevID: "dev1"}, {DevID: "dev2"}}})
+	Register("test2", &testDriver{})
+	require.Panics(t, func() { Register("test1", &testDriver{}) })
+
+	// all configured sources
+	srcs, err := Open(log.NewNopLogger(), nil)
+	require.NoError(t, err)
+	require.Len(t, srcs, 1)
+	require.Contains(t, srcs, "test1")
+
+	_, err = Open(log.NewNopLogger(), []string{"test1", "test2"})
+	require.Error(t, err)
+
+	_, err = Open(log.NewNopLogger(), []string{"test3"})
+	require.Error(t, err)
+
+	var devs []string
+	err = srcs["test1"].Run(context.Background(), Instrument("test1", func(ctx context.Context, u *Uplink) {
+		devs = append(devs, u.DevID)
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"dev1", "dev2"}, devs)
+	require.Equal(t, 2.0, testutil.ToFloat64(UplinkCounter.WithLabelValues("test1")))
+}
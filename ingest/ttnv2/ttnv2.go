@@ -0,0 +1,139 @@
+// Package ttnv2 receives the uplinks from the legacy The Things Network v2 pub/sub API
+package ttnv2
+
+import (
+	"context"
+	"time"
+
+	ttnsdk "github.com/TheThingsNetwork/go-app-sdk"
+	"github.com/TheThingsNetwork/ttn/core/types"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/namsral/flag"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+// Name is the source name in the ingest registry
+const Name = "ttnv2"
+
+// clientName identifies geottnd to the TTN v2 network
+const clientName = "geottnd"
+
+// ClientVersion is the version reported to the TTN v2 network, set by main
+var ClientVersion string
+
+func init() {
+	ingest.Register(Name, &driver{})
+}
+
+type driver struct {
+	appID        string
+	appAccessKey string
+}
+
+// Flags implements ingest.Driver
+func (d *driver) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&d.appID, "appID", "akhtestapp", "The things network application ID")
+	fs.StringVar(&d.appAccessKey, "appAccessKey", "", "The things network access key")
+}
+
+// Open implements ingest.Driver
+func (d *driver) Open(logger log.Logger) (ingest.Source, error) {
+	if d.appAccessKey == "" {
+		return nil, ingest.ErrNotConfigured
+	}
+	return &Source{
+		logger:       logger,
+		appID:        d.appID,
+		appAccessKey: d.appAccessKey,
+	}, nil
+}
+
+// Source subscribes to the uplinks of all the devices of a TTN v2 application
+type Source struct {
+	logger       log.Logger
+	appID        string
+	appAccessKey string
+}
+
+// Run implements ingest.Source
+func (s *Source) Run(ctx context.Context, h ingest.Handler) error {
+	config := ttnsdk.NewCommunityConfig(clientName)
+	config.ClientVersion = ClientVersion
+
+	// Create a new SDK client for the application
+	client := config.NewClient(s.appID, s.appAccessKey)
+
+	// Make sure the client is closed before the function returns
+	defer client.Close()
+
+	// Start Publish/Subscribe client (MQTT)
+	pubsub, err := client.PubSub()
+	if err != nil {
+		level.Error(s.logger).Log("msg", "can't get pub/sub", "error", err)
+		return err
+	}
+
+	// Make sure the pubsub client is closed before the function returns
+	defer pubsub.Close()
+
+	// Get a publish/subscribe client for all devices
+	allDevicesPubSub := pubsub.AllDevices()
+
+	// This also stops existing subscriptions, in case you forgot to unsubscribe
+	defer allDevicesPubSub.Close()
+
+	// Subscribe to msgs
+	msgs, err := allDevicesPubSub.SubscribeUplink()
+	if err != nil {
+		level.Error(s.logger).Log("msg", "can't subscribe to events", "error", err)
+		return err
+	}
+	level.Info(s.logger).Log("msg", "subscribed to uplink messages")
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Unsubscribe from events
+			level.Info(s.logger).Log("msg", "unsubscribing to uplink messages")
+
+			if err = allDevicesPubSub.UnsubscribeEvents(); err != nil {
+				level.Error(s.logger).Log("msg", "can't unsubscribe from events", "error", err)
+				return err
+			}
+			return nil
+		case msg := <-msgs:
+			if msg == nil {
+				break
+			}
+			h(ctx, Uplink(msg))
+		}
+	}
+}
+
+// Uplink converts a TTN v2 uplink message
+func Uplink(msg *types.UplinkMessage) *ingest.Uplink {
+	u := &ingest.Uplink{
+		AppID:     msg.AppID,
+		DevID:     msg.DevID,
+		FCnt:      msg.FCnt,
+		Payload:   msg.PayloadRaw,
+		Fields:    msg.PayloadFields,
+		Time:      time.Time(msg.Metadata.Time),
+		DataRate:  msg.Metadata.DataRate,
+		Frequency: msg.Metadata.Frequency,
+	}
+	for _, gw := range msg.Metadata.Gateways {
+		u.Gateways = append(u.Gateways, ingest.Gateway{
+			ID:   gw.GtwID,
+			Time: time.Time(gw.Time),
+			RSSI: gw.RSSI,
+			SNR:  gw.SNR,
+			Lat:  float64(gw.Latitude),
+			Lng:  float64(gw.Longitude),
+			Alt:  gw.Altitude,
+		})
+	}
+	return u
+}
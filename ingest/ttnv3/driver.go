@@ -0,0 +1,45 @@
+package ttnv3
+
+import (
+	"github.com/go-kit/kit/log"
+	"github.com/namsral/flag"
+
+	"github.com/akhenakh/geottn/ingest"
+	"github.com/akhenakh/geottn/ingest/mqtt"
+)
+
+// Name is the source name in the ingest registry
+const Name = "ttnv3"
+
+func init() {
+	ingest.Register(Name, &driver{})
+}
+
+type driver struct {
+	broker   string
+	username string
+	apiKey   string
+	topic    string
+}
+
+// Flags implements ingest.Driver
+func (d *driver) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&d.broker, "ttsBroker", "", "The Things Stack (TTN v3) MQTT broker, eg: tls://eu1.cloud.thethings.network:8883")
+	fs.StringVar(&d.username, "ttsUsername", "", "The Things Stack MQTT username, eg: myappid@ttn")
+	fs.StringVar(&d.apiKey, "ttsAPIKey", "", "The Things Stack MQTT API key")
+	fs.StringVar(&d.topic, "ttsTopic", DefaultTopic, "The Things Stack uplink topic")
+}
+
+// Open implements ingest.Driver
+func (d *driver) Open(logger log.Logger) (ingest.Source, error) {
+	if d.broker == "" {
+		return nil, ingest.ErrNotConfigured
+	}
+	cfg := mqtt.Config{
+		Broker:   d.broker,
+		Username: d.username,
+		Password: d.apiKey,
+		ClientID: "geottnd-" + Name,
+	}
+	return mqtt.NewDialSource(logger, Name, cfg, d.topic, Decode), nil
+}
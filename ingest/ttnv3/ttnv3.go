@@ -0,0 +1,122 @@
+// Package ttnv3 receives the uplinks from The Things Stack, TTN v3, MQTT integration
+package ttnv3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/kit/log"
+
+	"github.com/akhenakh/geottn/ingest"
+	"github.com/akhenakh/geottn/ingest/mqtt"
+)
+
+// DefaultTopic receives the uplinks of all the devices the credentials give access to
+const DefaultTopic = "v3/+/devices/+/up"
+
+// NewSource returns a source receiving the uplinks published on topic, DefaultTopic if empty
+func NewSource(logger log.Logger, client mqtt.Subscriber, topic string) *mqtt.Source {
+	if topic == "" {
+		topic = DefaultTopic
+	}
+	return mqtt.NewSource(logger, Name, client, topic, Decode)
+}
+
+// message is the subset of the v3 uplink message used
+type message struct {
+	EndDeviceIDs struct {
+		DeviceID       string `json:"device_id"`
+		ApplicationIDs struct {
+			ApplicationID string `json:"application_id"`
+		} `json:"application_ids"`
+	} `json:"end_device_ids"`
+	ReceivedAt    time.Time `json:"received_at"`
+	UplinkMessage *struct {
+		FCnt           uint32                 `json:"f_cnt"`
+		FRMPayload     []byte                 `json:"frm_payload"`
+		DecodedPayload map[string]interface{} `json:"decoded_payload"`
+		RxMetadata     []struct {
+			GatewayIDs struct {
+				GatewayID string `json:"gateway_id"`
+			} `json:"gateway_ids"`
+			Time        *time.Time `json:"time"`
+			RSSI        *float32   `json:"rssi"`
+			ChannelRSSI float32    `json:"channel_rssi"`
+			SNR         float32    `json:"snr"`
+			Location    *struct {
+				Latitude  float64 `json:"latitude"`
+				Longitude float64 `json:"longitude"`
+				Altitude  int32   `json:"altitude"`
+			} `json:"location"`
+		} `json:"rx_metadata"`
+		Settings struct {
+			DataRate struct {
+				LoRa *struct {
+					Bandwidth       uint32 `json:"bandwidth"`
+					SpreadingFactor uint32 `json:"spreading_factor"`
+				} `json:"lora"`
+			} `json:"data_rate"`
+			// in Hz, encoded as a string
+			Frequency string `json:"frequency"`
+		} `json:"settings"`
+	} `json:"uplink_message"`
+}
+
+// Decode parses a v3 uplink message as published on the up topic
+func Decode(b []byte) (*ingest.Uplink, error) {
+	var msg message
+	if err := json.Unmarshal(b, &msg); err != nil {
+		return nil, err
+	}
+	um := msg.UplinkMessage
+	if um == nil {
+		return nil, ingest.ErrNoUplink
+	}
+
+	u := &ingest.Uplink{
+		AppID:   msg.EndDeviceIDs.ApplicationIDs.ApplicationID,
+		DevID:   msg.EndDeviceIDs.DeviceID,
+		FCnt:    um.FCnt,
+		Payload: um.FRMPayload,
+		Fields:  um.DecodedPayload,
+		Time:    msg.ReceivedAt,
+	}
+	if u.DevID == "" {
+		return nil, errors.New("missing device_id")
+	}
+
+	if lora := um.Settings.DataRate.LoRa; lora != nil {
+		u.DataRate = fmt.Sprintf("SF%dBW%d", lora.SpreadingFactor, lora.Bandwidth/1000)
+	}
+	if um.Settings.Frequency != "" {
+		f, err := strconv.ParseUint(um.Settings.Frequency, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid frequency: %w", err)
+		}
+		u.Frequency = float32(float64(f) / 1e6)
+	}
+
+	for _, rx := range um.RxMetadata {
+		gw := ingest.Gateway{
+			ID:   rx.GatewayIDs.GatewayID,
+			RSSI: rx.ChannelRSSI,
+			SNR:  rx.SNR,
+		}
+		if rx.RSSI != nil {
+			gw.RSSI = *rx.RSSI
+		}
+		if rx.Time != nil {
+			gw.Time = *rx.Time
+		}
+		if rx.Location != nil {
+			gw.Lat = rx.Location.Latitude
+			gw.Lng = rx.Location.Longitude
+			gw.Alt = rx.Location.Altitude
+		}
+		u.Gateways = append(u.Gateways, gw)
+	}
+	return u, nil
+}
@@ -0,0 +1,104 @@
+package ttnv3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/ingest"
+	"github.com/akhenakh/geottn/ingest/mqtt/mqtttest"
+)
+
+const upMsg = `{
+  "end_device_ids": {
+    "device_id": "tracker1",
+    "application_ids": {"application_id": "trackers"},
+    "dev_eui": "70B3D57ED0000001"
+  },
+  "received_at": "2020-11-22T10:00:01.5Z",
+  "uplink_message": {
+    "f_port": 1,
+    "f_cnt": 42,
+    "frm_payload": "AYgHqSAA1tgAAAA=",
+    "decoded_payload": {"gps_1": {"latitude": 50.2048, "longitude": 5.5, "altitude": 0}},
+    "rx_metadata": [
+      {
+        "gateway_ids": {"gateway_id": "gw1", "eui": "B827EBFFFE000001"},
+        "time": "2020-11-22T10:00:01Z",
+        "rssi": -110,
+        "channel_rssi": -110,
+        "snr": -2.5,
+        "location": {"latitude": 48.85, "longitude": 2.35, "altitude": 35, "source": "SOURCE_REGISTRY"}
+      },
+      {
+        "gateway_ids": {"gateway_id": "gw2"},
+        "channel_rssi": -95,
+        "snr": 7
+      }
+    ],
+    "settings": {
+      "data_rate": {"lora": {"bandwidth": 125000, "spreading_factor": 7}},
+      "coding_rate": "4/5",
+      "frequency": "868100000"
+    },
+    "received_at": "2020-11-22T10:00:01.4Z"
+  }
+}`
+
+func TestDecode(t *testing.T) {
+	u, err := Decode([]byte(upMsg))
+	require.NoError(t, err)
+	require.Equal(t, "trackers", u.AppID)
+	require.Equal(t, "tracker1", u.DevID)
+	require.Equal(t, uint32(42), u.FCnt)
+	require.Equal(t, []byte{1, 136, 7, 169, 32, 0, 214, 216, 0, 0, 0}, u.Payload)
+	require.Contains(t, u.Fields, "gps_1")
+	require.Equal(t, time.Date(2020, 11, 22, 10, 0, 1, 5e8, time.UTC), u.Time)
+	require.Equal(t, "SF7BW125", u.DataRate)
+	require.InDelta(t, 868.1, u.Frequency, 0.0001)
+	require.Len(t, u.Gateways, 2)
+	require.Equal(t, "gw1", u.Gateways[0].ID)
+	require.Equal(t, time.Date(2020, 11, 22, 10, 0, 1, 0, time.UTC), u.Gateways[0].Time)
+	require.Equal(t, float32(-110), u.Gateways[0].RSSI)
+	require.Equal(t, 48.85, u.Gateways[0].Lat)
+	require.Equal(t, int32(35), u.Gateways[0].Alt)
+	require.Equal(t, float32(-95), u.Gateways[1].RSSI)
+	require.True(t, u.Gateways[1].Time.IsZero())
+
+	_, err = Decode([]byte(`{"end_device_ids": {"device_id": "tracker1"}, "join_accept": {}}`))
+	require.Equal(t, ingest.ErrNoUplink, err)
+}
+
+func TestSource(t *testing.T) {
+	b := mqtttest.NewBroker()
+	s := NewSource(log.NewNopLogger(), b, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	uplinks := make(chan *ingest.Uplink, 1)
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx, func(ctx context.Context, u *ingest.Uplink) {
+			uplinks <- u
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		return b.Subscriptions() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// invalid messages are skipped
+	b.Publish("v3/trackers@ttn/devices/tracker1/up", []byte("{"))
+	b.Publish("v3/trackers@ttn/devices/tracker1/join", []byte(upMsg))
+	b.Publish("v3/trackers@ttn/devices/tracker1/up", []byte(upMsg))
+
+	u := <-uplinks
+	require.Equal(t, "tracker1", u.DevID)
+	require.Len(t, uplinks, 0)
+
+	cancel()
+	require.NoError(t, <-done)
+	require.Equal(t, 0, b.Subscriptions())
+}
@@ -0,0 +1,170 @@
+// Package webhook receives the uplinks posted by the network servers HTTP integrations
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/namsral/flag"
+
+	"github.com/akhenakh/geottn/ingest"
+	"github.com/akhenakh/geottn/ingest/chirpstack"
+	"github.com/akhenakh/geottn/ingest/helium"
+	"github.com/akhenakh/geottn/ingest/loriot"
+	"github.com/akhenakh/geottn/ingest/ttnv3"
+)
+
+// Name is the source name in the ingest registry
+const Name = "webhook"
+
+// SignatureHeader is the header carrying the hexadecimal HMAC-SHA256 of the body,
+// optionally prefixed by sha256=
+const SignatureHeader = "X-Signature"
+
+const maxBodySize = 1 << 20
+
+// Formats are the decoders of the supported payloads, by the name used in the URL
+var Formats = map[string]func([]byte) (*ingest.Uplink, error){
+	"tts":        ttnv3.Decode,
+	"chirpstack": chirpstack.Decode,
+	"helium":     helium.Decode,
+	"loriot":     loriot.Decode,
+}
+
+func init() {
+	ingest.Register(Name, &driver{})
+}
+
+type driver struct {
+	secret string
+}
+
+// Flags implements ingest.Driver
+func (d *driver) Flags(fs *flag.FlagSet) {
+	fs.StringVar(&d.secret, "webhookSecret", "", "the shared secret of the /ingest/webhook/{format} webhooks, disabled if empty")
+}
+
+// Open implements ingest.Driver
+func (d *driver) Open(logger log.Logger) (ingest.Source, error) {
+	if d.secret == "" {
+		return nil, ingest.ErrNotConfigured
+	}
+	return NewSource(logger, d.secret), nil
+}
+
+// Source is an http.Handler receiving the uplinks posted to /ingest/webhook/{format},
+// requests are authenticated by an Authorization Bearer header with the secret
+// or by an HMAC of the body signed with the secret in SignatureHeader
+type Source struct {
+	logger log.Logger
+	secret []byte
+
+	mu sync.RWMutex
+	h  ingest.Handler
+}
+
+// NewSource returns a webhook source authenticating the requests with secret
+func NewSource(logger log.Logger, secret string) *Source {
+	return &Source{
+		logger: logger,
+		secret: []byte(secret),
+	}
+}
+
+// Run implements ingest.Source, the uplinks are received until ctx is done
+func (s *Source) Run(ctx context.Context, h ingest.Handler) error {
+	s.mu.Lock()
+	s.h = h
+	s.mu.Unlock()
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	s.h = nil
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Source) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	format := path.Base(r.URL.Path)
+	decode, ok := Formats[format]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxBodySize {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if !s.authorized(r, body) {
+		level.Warn(s.logger).Log("msg", "unauthorized webhook request", "format", format, "remote", r.RemoteAddr)
+		ingest.ErrorCounter.WithLabelValues(Name).Inc()
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	u, err := decode(body)
+	if err == ingest.ErrNoUplink {
+		// joins, status... are posted to the same endpoint
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if err != nil {
+		level.Warn(s.logger).Log("msg", "can't decode uplink", "format", format, "error", err)
+		ingest.ErrorCounter.WithLabelValues(Name).Inc()
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	s.mu.RLock()
+	h := s.h
+	s.mu.RUnlock()
+	if h == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	h(r.Context(), u)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// authorized verifies the signature of body, or the bearer token if the request is not signed
+func (s *Source) authorized(r *http.Request, body []byte) bool {
+	if sig := r.Header.Get(SignatureHeader); sig != "" {
+		got, err := hex.DecodeString(strings.TrimPrefix(sig, "sha256="))
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	}
+
+	auth := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(auth, "Bearer ")
+	return token != auth && subtle.ConstantTimeCompare([]byte(token), s.secret) == 1
+}
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/ingest"
+)
+
+const heliumMsg = `{"type": "uplink", "dev_eui": "7070707070707001", "fcnt": 1, "payload": "AQ=="}`
+
+func TestWebhook(t *testing.T) {
+	s := NewSource(log.NewNopLogger(), "secret")
+
+	post := func(format, body string, headers ...string) int {
+		req := httptest.NewRequest(http.MethodPost, "/ingest/webhook/"+format, strings.NewReader(body))
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// not running
+	require.Equal(t, http.StatusServiceUnavailable, post("helium", heliumMsg, "Authorization", "Bearer secret"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	uplinks := make(chan *ingest.Uplink, 10)
+	go s.Run(ctx, func(ctx context.Context, u *ingest.Uplink) {
+		uplinks <- u
+	})
+	require.Eventually(t, func() bool {
+		return post("helium", heliumMsg, "Authorization", "Bearer secret") == http.StatusAccepted
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, "7070707070707001", (<-uplinks).DevID)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(heliumMsg))
+	sig := hex.EncodeToString(mac.Sum(nil))
+	require.Equal(t, http.StatusAccepted, post("helium", heliumMsg, SignatureHeader, "sha256="+sig))
+	require.Equal(t, "7070707070707001", (<-uplinks).DevID)
+
+	require.Equal(t, http.StatusUnauthorized, post("helium", heliumMsg))
+	require.Equal(t, http.StatusUnauthorized, post("helium", heliumMsg, "Authorization", "Bearer nope"))
+	require.Equal(t, http.StatusUnauthorized, post("helium", heliumMsg, "Authorization", "secret"))
+	require.Equal(t, http.StatusUnauthorized, post("helium", heliumMsg+" ", SignatureHeader, sig))
+
+	require.Equal(t, http.StatusNotFound, post("kerlink", heliumMsg, "Authorization", "Bearer secret"))
+	require.Equal(t, http.StatusBadRequest, post("helium", "{", "Authorization", "Bearer secret"))
+	require.Equal(t, http.StatusNoContent, post("helium", `{"type": "join"}`, "Authorization", "Bearer secret"))
+	require.Len(t, uplinks, 0)
+}
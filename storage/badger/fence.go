@@ -0,0 +1,213 @@
+package badger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+// StoreFence stores or replaces the fence f
+func (idx *Indexer) StoreFence(f *storage.Geofence) error {
+	if f.ID == "" {
+		return storage.ErrInvalidFence
+	}
+	if _, err := f.Region(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+
+	return idx.Update(func(txn *badger.Txn) error {
+		return txn.Set(storage.FenceKey(f.ID), b)
+	})
+}
+
+// GetFence returns the fence id
+func (idx *Indexer) GetFence(id string) (*storage.Geofence, error) {
+	var f storage.Geofence
+	err := idx.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(storage.FenceKey(id))
+		if err == badger.ErrKeyNotFound {
+			return storage.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &f)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Fences returns all the fences
+func (idx *Indexer) Fences() ([]storage.Geofence, error) {
+	var res []storage.Geofence
+	err := idx.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+		prefix := storage.FenceKey("")
+
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var f storage.Geofence
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &f)
+			})
+			if err != nil {
+				return err
+			}
+			res = append(res, f)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteFence removes the fence id and all its events
+func (idx *Indexer) DeleteFence(id string) error {
+	var keys [][]byte
+	err := idx.View(func(txn *badger.Txn) error {
+		prefix := storage.FenceEventKey(id, storage.MaxGeoTime, "")
+		// get rid of the last 64bits of ts to iterate on the prefix
+		prefix = prefix[:len(prefix)-8]
+		var err error
+		keys, err = eventKeys(txn, prefix)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return idx.deleteKeys(append(keys, storage.FenceKey(id)))
+}
+
+// StoreFenceEventTx stores the event e, listed by fence and by device
+func (idx *Indexer) StoreFenceEventTx(txi storage.Tx, e *storage.GeofenceEvent) error {
+	tx, ok := txi.(*badger.Txn)
+	if !ok {
+		return errors.New("invalid tx passed")
+	}
+
+	var expiresAt uint64
+	if d := idx.retention(e.Key); d > 0 {
+		expiresAt = uint64(e.Time.Add(d).Unix())
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	en := badger.NewEntry(storage.FenceEventKey(e.FenceID, e.Time, e.Key), b)
+	en.ExpiresAt = expiresAt
+	err = tx.SetEntry(en)
+	if err == nil {
+		en = badger.NewEntry(storage.DeviceFenceEventKey(e.Key, e.Time, e.FenceID), b)
+		en.ExpiresAt = expiresAt
+		err = tx.SetEntry(en)
+	}
+	if err == badger.ErrTxnTooBig {
+		return storage.ErrTxTooBig
+	}
+	return err
+}
+
+// FenceEvents returns the events for the fence id between start and end, most recent first,
+// up to count starting after cursor
+func (idx *Indexer) FenceEvents(id string, start, end time.Time, cursor []byte, count int) ([]storage.GeofenceEvent, []byte, error) {
+	return idx.eventsRange(storage.FenceEventKey(id, end, ""), start, cursor, count)
+}
+
+// DeviceFenceEvents returns the fence events for the device k between start and end, most recent first,
+// up to count starting after cursor
+func (idx *Indexer) DeviceFenceEvents(k string, start, end time.Time, cursor []byte, count int) ([]storage.GeofenceEvent, []byte, error) {
+	return idx.eventsRange(storage.DeviceFenceEventKey(k, end, ""), start, cursor, count)
+}
+
+// eventsRange iterates over events from seek until start
+func (idx *Indexer) eventsRange(seek []byte, start time.Time, cursor []byte, count int) ([]storage.GeofenceEvent, []byte, error) {
+	var res []storage.GeofenceEvent
+	var last, next []byte
+	// get rid of the last 64bits of ts to iterate on the prefix
+	prefix := seek[:len(seek)-8]
+	if cursor != nil {
+		if !bytes.HasPrefix(cursor, prefix) {
+			return nil, nil, storage.ErrInvalidCursor
+		}
+		seek = cursor
+	}
+
+	err := idx.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		for it.Seek(seek); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			if cursor != nil && bytes.Equal(item.Key(), cursor) {
+				continue
+			}
+
+			var e storage.GeofenceEvent
+			err := item.Value(func(val []byte) error {
+				return json.Unmarshal(val, &e)
+			})
+			if err != nil {
+				return err
+			}
+
+			// older than start, we are done
+			if e.Time.Before(start) {
+				break
+			}
+
+			// there are more entries, the last returned one is the cursor
+			if count > 0 && len(res) >= count {
+				next = last
+				break
+			}
+
+			res = append(res, e)
+			last = item.KeyCopy(nil)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, next, nil
+}
+
+// eventKeys returns the keys of the events starting with prefix and of their counterparts in the other listing
+func eventKeys(txn *badger.Txn, prefix []byte) ([][]byte, error) {
+	var keys [][]byte
+	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	defer it.Close()
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		var e storage.GeofenceEvent
+		err := it.Item().Value(func(val []byte) error {
+			return json.Unmarshal(val, &e)
+		})
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys,
+			storage.FenceEventKey(e.FenceID, e.Time, e.Key),
+			storage.DeviceFenceEventKey(e.Key, e.Time, e.FenceID),
+		)
+	}
+	return keys, nil
+}
@@ -0,0 +1,117 @@
+package badger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/storage"
+)
+
+func TestFences(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+
+	err := idx.StoreFence(&storage.Geofence{ID: "circle", Name: "Circle", Lat: 48.8, Lng: 2.2, Radius: 1000})
+	require.NoError(t, err)
+	err = idx.StoreFence(&storage.Geofence{ID: "polygon", Name: "Polygon", Rings: [][]s2.LatLng{{
+		s2.LatLngFromDegrees(48.0, 2.0),
+		s2.LatLngFromDegrees(48.0, 3.0),
+		s2.LatLngFromDegrees(49.0, 3.0),
+	}}})
+	require.NoError(t, err)
+
+	// neither a circle nor a polygon
+	err = idx.StoreFence(&storage.Geofence{ID: "invalid"})
+	require.Equal(t, storage.ErrInvalidFence, err)
+
+	f, err := idx.GetFence("polygon")
+	require.NoError(t, err)
+	require.Equal(t, "Polygon", f.Name)
+	require.Len(t, f.Rings[0], 3)
+
+	_, err = idx.GetFence("missing")
+	require.Equal(t, storage.ErrNotFound, err)
+
+	fences, err := idx.Fences()
+	require.NoError(t, err)
+	require.Len(t, fences, 2)
+
+	err = idx.DeleteFence("polygon")
+	require.NoError(t, err)
+	fences, err = idx.Fences()
+	require.NoError(t, err)
+	require.Len(t, fences, 1)
+	require.Equal(t, "circle", fences[0].ID)
+}
+
+func TestFenceEvents(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+
+	events := []storage.GeofenceEvent{
+		{FenceID: "depot", Key: "KEY", Type: storage.FenceEnter, Time: ts},
+		{FenceID: "depot", Key: "KEY", Type: storage.FenceExit, Time: ts.Add(time.Hour)},
+		{FenceID: "depot", Key: "KEY2", Type: storage.FenceEnter, Time: ts.Add(2 * time.Hour)},
+		{FenceID: "farm", Key: "KEY", Type: storage.FenceEnter, Time: ts.Add(3 * time.Hour)},
+	}
+	tx := idx.Begin()
+	for _, e := range events {
+		e := e
+		err := idx.StoreFenceEventTx(tx, &e)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tx.Commit())
+
+	res, next, err := idx.FenceEvents("depot", storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Nil(t, next)
+	require.Len(t, res, 3)
+	// most recent first
+	require.Equal(t, "KEY2", res[0].Key)
+	require.Equal(t, storage.FenceExit, res[1].Type)
+
+	res, next, err = idx.FenceEvents("depot", storage.MinGeoTime, storage.MaxGeoTime, nil, 2)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	res, next, err = idx.FenceEvents("depot", storage.MinGeoTime, storage.MaxGeoTime, next, 2)
+	require.NoError(t, err)
+	require.Nil(t, next)
+	require.Len(t, res, 1)
+	require.Equal(t, ts, res[0].Time)
+
+	res, _, err = idx.DeviceFenceEvents("KEY", ts.Add(30*time.Minute), storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, "farm", res[0].FenceID)
+	require.Equal(t, "depot", res[1].FenceID)
+
+	// deleting the device removes its events on both listings
+	err = idx.Delete("KEY")
+	require.NoError(t, err)
+	res, _, err = idx.DeviceFenceEvents("KEY", storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 0)
+	res, _, err = idx.FenceEvents("depot", storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, "KEY2", res[0].Key)
+
+	// deleting the fence removes its events on both listings
+	err = idx.DeleteFence("depot")
+	require.NoError(t, err)
+	res, _, err = idx.DeviceFenceEvents("KEY2", storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 0)
+}
@@ -2,7 +2,10 @@ package badger
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"math"
+	"sort"
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
@@ -11,20 +14,59 @@ import (
 	"github.com/akhenakh/geottn/storage"
 )
 
+// nearestStartLevel is the cell level the nearest search starts at, about 150m wide cells
+const nearestStartLevel = 16
+
 type Indexer struct {
 	*badger.DB
+
+	// Retention is the duration entries are kept after their time, 0 to keep them forever
+	Retention time.Duration
+
+	// DeviceRetention overrides Retention for specific keys
+	DeviceRetention map[string]time.Duration
+}
+
+// retention returns the retention duration for k
+func (idx *Indexer) retention(k string) time.Duration {
+	if d, ok := idx.DeviceRetention[k]; ok {
+		return d
+	}
+	return idx.Retention
 }
 
 // StoreTx is storing k and v but also geoindex at lat lng for the  most recent entry
+// every entries are also stored in the historical geoindex
+// when a retention is set, entries are expiring after t + retention
+// an older entry is only stored in the data and historical indexes
+// storage.ErrTxTooBig is returned when tx can't hold this entry, tx should then be discarded
 func (idx *Indexer) StoreTx(txi storage.Tx, k string, v []byte, lat, lng float64, t time.Time) error {
 	tx, ok := txi.(*badger.Txn)
 	if !ok {
 		return errors.New("invalid tx passed")
 	}
 
+	err := idx.storeTx(tx, k, v, lat, lng, t)
+	if err == badger.ErrTxnTooBig {
+		return storage.ErrTxTooBig
+	}
+	return err
+}
+
+func (idx *Indexer) storeTx(tx *badger.Txn, k string, v []byte, lat, lng float64, t time.Time) error {
+
+	expiresAt, ok := idx.expiresAt(k, t)
+	// already past the retention, nothing to store
+	if !ok {
+		return storage.ErrExpired
+	}
+
 	// the geo key G
 	pk := storage.PointKey(lat, lng, t, k)
 
+	// the historical geo key H
+	hk := storage.HistoryKey(lat, lng, t, k)
+
 	// the datakey D
 	dk := storage.DataKey(k, t, lat, lng)
 
@@ -32,59 +74,158 @@ func (idx *Indexer) StoreTx(txi storage.Tx, k string, v []byte, lat, lng float64
 	kk := storage.ListKey(k)
 
 	// Check for existing
-	// get rid of the last 64bits ts and 64bits s2 cell to iterate on the prefix
-	prefix := dk[:len(dk)-8-8]
-	opts := badger.DefaultIteratorOptions
-	opts.PrefetchValues = false
-	it := tx.NewIterator(opts)
-	defer it.Close()
-
-	exist := false
-
-	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-		exist = true
-		item := it.Item()
-		ck := item.KeyCopy(nil)
+	ldk, lexp, err := idx.latestDataKey(tx, k)
+	if err != nil {
+		return err
+	}
+	exist := ldk != nil
+	latest := true
 
-		// already existing key while iterating D ?
-		// if ck is dk we dont want to erase it since it's the exact same entry
-		if bytes.Equal(ck, dk) {
+	if exist {
+		// if ldk is dk we dont want to erase it since it's the exact same entry
+		if bytes.Equal(ldk, dk) {
 			return nil
 		}
 
-		// always delete associated G entry since we ever want one at a time on the geo index
-		ek, et, elat, elng, err := storage.ReadDataKey(ck)
+		ek, et, elat, elng, err := storage.ReadDataKey(ldk)
 		if err != nil {
 			return err
 		}
-		epk := storage.PointKey(elat, elng, et, ek)
-		if err := tx.Delete(epk); err != nil {
-			return err
+
+		if et.After(t) {
+			// a more recent entry keeps its G entry
+			latest = false
+		} else {
+			// delete associated G entry since we ever want one at a time on the geo index
+			epk := storage.PointKey(elat, elng, et, ek)
+			if err := tx.Delete(epk); err != nil {
+				return err
+			}
 		}
 	}
 
+	var e *badger.Entry
+
 	// storing G
-	e := badger.NewEntry(pk, v)
+	if latest {
+		e = badger.NewEntry(pk, v)
+		e.ExpiresAt = expiresAt
+		if err := tx.SetEntry(e); err != nil {
+			return err
+		}
+	}
+
+	// storing H
+	e = badger.NewEntry(hk, v)
+	e.ExpiresAt = expiresAt
 	if err := tx.SetEntry(e); err != nil {
 		return err
 	}
 
 	// storing D
 	e = badger.NewEntry(dk, v)
+	e.ExpiresAt = expiresAt
 	if err := tx.SetEntry(e); err != nil {
 		return err
 	}
 
-	// storing L
+	// storing L, its value is the most recent D
 	if !exist {
-		e = badger.NewEntry(kk, nil)
-		if err := tx.SetEntry(e); err != nil {
-			return err
-		}
+		e = badger.NewEntry(kk, dk)
+		e.ExpiresAt = expiresAt
+		return tx.SetEntry(e)
+	}
+
+	lv := ldk
+	if latest {
+		lv = dk
+	}
+	// L should live as long as the most recent D, never expiring with it
+	// otherwise keep its expiration if it's never expiring (entries stored without retention) or expiring later
+	if latest && expiresAt == 0 {
+		lexp = 0
+	} else if expiresAt != 0 && lexp != 0 && lexp < expiresAt {
+		lexp = expiresAt
+	}
+	e = badger.NewEntry(kk, lv)
+	e.ExpiresAt = lexp
+	return tx.SetEntry(e)
+}
+
+// latestDataKey returns the most recent D key for k or nil, and the expiration of the L key,
+// using the L value, or iterating over D for L entries written without value
+func (idx *Indexer) latestDataKey(tx *badger.Txn, k string) ([]byte, uint64, error) {
+	item, err := tx.Get(storage.ListKey(k))
+	if err == badger.ErrKeyNotFound {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	exp := item.ExpiresAt()
 
+	dk, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(dk) > 0 {
+		return dk, exp, nil
 	}
-	return nil
 
+	// entries are most recent first
+	prefix := storage.DataKey(k, time.Time{}, 0, 0)
+	// get rid of the last 64bits ts and 64bits s2 cell to iterate on the prefix
+	prefix = prefix[:len(prefix)-8-8]
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := tx.NewIterator(opts)
+	defer it.Close()
+
+	it.Seek(prefix)
+	if !it.ValidForPrefix(prefix) {
+		return nil, exp, nil
+	}
+	return it.Item().KeyCopy(nil), exp, nil
+}
+
+// StoreMetaTx is storing the metadata m of the entry k at t, expiring with the entry
+func (idx *Indexer) StoreMetaTx(txi storage.Tx, k string, lat, lng float64, t time.Time, m *storage.Metadata) error {
+	tx, ok := txi.(*badger.Txn)
+	if !ok {
+		return errors.New("invalid tx passed")
+	}
+
+	expiresAt, ok := idx.expiresAt(k, t)
+	if !ok {
+		return nil
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	e := badger.NewEntry(storage.MetaKey(k, t, lat, lng), b)
+	e.ExpiresAt = expiresAt
+	err = tx.SetEntry(e)
+	if err == badger.ErrTxnTooBig {
+		return storage.ErrTxTooBig
+	}
+	return err
+}
+
+// expiresAt returns the expiration of the entry k at t, 0 if never expiring,
+// false if already expired
+func (idx *Indexer) expiresAt(k string, t time.Time) (uint64, bool) {
+	d := idx.retention(k)
+	if d <= 0 {
+		return 0, true
+	}
+	exp := t.Add(d)
+	if !exp.After(time.Now()) {
+		return 0, false
+	}
+	return uint64(exp.Unix()), true
 }
 
 // Store is storing k and v but also geoindex at lat lng
@@ -101,49 +242,130 @@ func (idx *Indexer) Store(k string, v []byte, lat, lng float64, t time.Time) err
 
 // GetAll return all entries for k up to count
 func (idx *Indexer) GetAll(k string, count int) ([]storage.DataPoint, error) {
+	return idx.GetRange(k, storage.MinGeoTime, storage.MaxGeoTime, count)
+}
+
+// GetRange return entries for k between start and end, most recent first, up to count
+func (idx *Indexer) GetRange(k string, start, end time.Time, count int) ([]storage.DataPoint, error) {
+	res, _, err := idx.GetRangePage(k, start, end, nil, count)
+	return res, err
+}
+
+// GetRangePage return entries for k between start and end, most recent first, up to count
+// starting after cursor, the returned cursor is nil when there are no more entries
+func (idx *Indexer) GetRangePage(k string, start, end time.Time, cursor []byte, count int) ([]storage.DataPoint, []byte, error) {
+	return idx.getRangePage(k, start, end, cursor, count, false)
+}
+
+// GetRangePageAsc is GetRangePage oldest first
+func (idx *Indexer) GetRangePageAsc(k string, start, end time.Time, cursor []byte, count int) ([]storage.DataPoint, []byte, error) {
+	return idx.getRangePage(k, start, end, cursor, count, true)
+}
+
+func (idx *Indexer) getRangePage(k string, start, end time.Time, cursor []byte, count int, asc bool) ([]storage.DataPoint, []byte, error) {
 	var res []storage.DataPoint
-	existing := 0
+	var last, next []byte
 	err := idx.View(func(txn *badger.Txn) error {
 		opts := badger.DefaultIteratorOptions
 		opts.PrefetchSize = count
 		if opts.PrefetchSize <= 0 {
 			opts.PrefetchSize = 10
 		}
+		// timestamps are reversed, oldest first is iterating the keys backward
+		opts.Reverse = asc
 		it := txn.NewIterator(opts)
 		defer it.Close()
-		prefix := storage.DataKey(k, storage.MaxGeoTime, 0.0, 0.0)
+		prefix := storage.DataKey(k, end, 0.0, 0.0)
+		// timestamps are reversed, seeking to end is the most recent entry in range
+		// get rid of the last 64bits of cell to seek
+		seek := prefix[:len(prefix)-8]
+		if asc {
+			// seeking backward to the last cell of start is the oldest entry in range
+			seek = storage.DataKey(k, start, 0.0, 0.0)
+			copy(seek[len(seek)-8:], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+		}
 		// get rid of the last 64bits of ts and 64 bits of cell to iterate on the prefix
 		prefix = prefix[:len(prefix)-8-8]
-		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-			if count > 0 && existing >= count {
-				break
+		if cursor != nil {
+			if !bytes.HasPrefix(cursor, prefix) {
+				return storage.ErrInvalidCursor
 			}
-
+			seek = cursor
+		}
+		for it.Seek(seek); it.ValidForPrefix(prefix); it.Next() {
 			item := it.Item()
+			if cursor != nil && bytes.Equal(item.Key(), cursor) {
+				continue
+			}
+
 			k := item.KeyCopy(nil)
 			dk, t, lat, lng, err := storage.ReadDataKey(k)
 			if err != nil {
 				return err
 			}
 
+			// out of range, we are done
+			if (!asc && t.Before(start)) || (asc && t.After(end)) {
+				break
+			}
+
+			// there are more entries, the last returned one is the cursor
+			if count > 0 && len(res) >= count {
+				next = last
+				break
+			}
+
 			valc, err := item.ValueCopy(nil)
 			if err != nil {
 				return err
 			}
+			meta, err := getMeta(txn, metaKey(k))
+			if err != nil {
+				return err
+			}
 			dt := storage.DataPoint{
 				Time:  t,
 				Value: valc,
 				Lat:   lat,
 				Lng:   lng,
 				Key:   dk,
+				Meta:  meta,
 			}
 			res = append(res, dt)
-			existing++
+			last = k
 		}
 		return nil
 	})
 
-	return res, err
+	return res, next, err
+}
+
+// getMeta returns the metadata stored under mk, nil if none
+func getMeta(txn *badger.Txn, mk []byte) (*storage.Metadata, error) {
+	item, err := txn.Get(mk)
+	if err == badger.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var m storage.Metadata
+	err = item.Value(func(val []byte) error {
+		return json.Unmarshal(val, &m)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// metaKey returns the M key matching the data key dk
+func metaKey(dk []byte) []byte {
+	mk := make([]byte, len(dk))
+	copy(mk, dk)
+	mk[len(storage.Prefix)] = 'M'
+	return mk
 }
 
 // Get the most recent entry for k
@@ -158,6 +380,105 @@ func (idx *Indexer) Get(k string) (*storage.DataPoint, error) {
 	return &res[0], err
 }
 
+// Delete removes all entries for k, including its fence events, in a single transaction.
+// When too big for a transaction the entries are deleted in several ones,
+// scanning again until none is left, and the listing entry last:
+// a failure leaves a part of the history, deleted by calling Delete again.
+func (idx *Indexer) Delete(k string) error {
+	err := idx.Update(func(txn *badger.Txn) error {
+		keys, err := deviceKeys(txn, k)
+		if err != nil {
+			return err
+		}
+		for _, key := range append(keys, storage.ListKey(k)) {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != badger.ErrTxnTooBig {
+		return err
+	}
+
+	for {
+		var keys [][]byte
+		err := idx.View(func(txn *badger.Txn) error {
+			var err error
+			keys, err = deviceKeys(txn, k)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			break
+		}
+		if err := idx.deleteKeys(keys); err != nil {
+			return err
+		}
+	}
+	return idx.deleteKeys([][]byte{storage.ListKey(k)})
+}
+
+// deviceKeys returns the keys of all the entries for k but its listing entry
+func deviceKeys(txn *badger.Txn, k string) ([][]byte, error) {
+	var keys [][]byte
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	prefix := storage.DataKey(k, storage.MaxGeoTime, 0.0, 0.0)
+	// get rid of the last 64bits of ts and 64 bits of cell to iterate on the prefix
+	prefix = prefix[:len(prefix)-8-8]
+
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		dk := it.Item().KeyCopy(nil)
+		ek, et, elat, elng, err := storage.ReadDataKey(dk)
+		if err != nil {
+			it.Close()
+			return nil, err
+		}
+		// only the most recent entry has a G entry, deleting a missing key is a no op
+		keys = append(keys,
+			storage.PointKey(elat, elng, et, ek),
+			storage.HistoryKey(elat, elng, et, ek),
+			dk,
+			metaKey(dk),
+		)
+	}
+	it.Close()
+
+	eprefix := storage.DeviceFenceEventKey(k, storage.MaxGeoTime, "")
+	// get rid of the last 64bits of ts to iterate on the prefix
+	eprefix = eprefix[:len(eprefix)-8]
+	eks, err := eventKeys(txn, eprefix)
+	if err != nil {
+		return nil, err
+	}
+	return append(keys, eks...), nil
+}
+
+// deleteKeys deletes keys in order, committing every time the transaction is full
+func (idx *Indexer) deleteKeys(keys [][]byte) error {
+	txn := idx.NewTransaction(true)
+	defer func() { txn.Discard() }()
+
+	for _, key := range keys {
+		err := txn.Delete(key)
+		if err == badger.ErrTxnTooBig {
+			if err := txn.Commit(); err != nil {
+				return err
+			}
+			txn = idx.NewTransaction(true)
+			err = txn.Delete(key)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return txn.Commit()
+}
+
 // Keys list all keys
 func (idx *Indexer) Keys() ([]string, error) {
 	var res []string
@@ -184,116 +505,230 @@ func (idx *Indexer) Keys() ([]string, error) {
 	return res, nil
 }
 
-// RectPointSearch returns all Points contained in the rect
+// RectSearch returns all Points contained in the rect
 func (idx *Indexer) RectSearch(urlat, urlng, bllat, bllng float64) ([]storage.DataPoint, error) {
+	res, _, err := idx.RectSearchPage(urlat, urlng, bllat, bllng, nil, 0)
+	return res, err
+}
+
+// RectSearchPage returns Points contained in the rect, up to count starting after cursor
+func (idx *Indexer) RectSearchPage(urlat, urlng, bllat, bllng float64, cursor []byte, count int) ([]storage.DataPoint, []byte, error) {
 	rect := s2.RectFromLatLng(s2.LatLngFromDegrees(bllat, bllng))
 	rect = rect.AddPoint(s2.LatLngFromDegrees(urlat, urlng))
 	coverer := &s2.RegionCoverer{MaxCells: 8}
 	cu := coverer.Covering(rect)
-	var res []storage.DataPoint
 
-	for _, c := range cu {
-		// a key prefix+"G"+cellid
-		start := make([]byte, len(storage.Prefix)+1+8)
-		copy(start, storage.Prefix+"G")
-		copy(start[len(storage.Prefix)+1:], storage.Uint64tob(uint64(c.RangeMin())))
-		stop := make([]byte, len(storage.Prefix)+1+8)
-		copy(stop, storage.Prefix+"G")
-		copy(stop[len(storage.Prefix)+1:], storage.Uint64tob(uint64(c.RangeMax())))
+	return idx.searchCells("G", cu, rect.ContainsPoint, storage.MinGeoTime, storage.MaxGeoTime, cursor, count)
+}
 
-		err := idx.View(func(txn *badger.Txn) error {
-			opts := badger.DefaultIteratorOptions
-			opts.PrefetchValues = true
-			it := txn.NewIterator(opts)
-			defer it.Close()
-			for it.Seek(start); it.Valid(); it.Next() {
-				item := it.Item()
-				k := item.Key()
-				if bytes.Compare(k, stop) > 0 {
-					break
-				}
-				ck := item.KeyCopy(nil)
-				c, t, rk, _ := storage.ReadPointKey(ck)
-				if rect.ContainsPoint(c.Point()) {
-					p := storage.DataPoint{
-						Lat:  c.LatLng().Lat.Degrees(),
-						Lng:  c.LatLng().Lng.Degrees(),
-						Time: t,
-						Key:  rk,
-					}
+// RadiusSearch returns the Points found in the index inside radius
+func (idx *Indexer) RadiusSearch(lat, lng, radius float64) ([]storage.DataPoint, error) {
+	res, _, err := idx.RadiusSearchPage(lat, lng, radius, nil, 0)
+	return res, err
+}
 
-					cv, err := item.ValueCopy(nil)
-					if err != nil {
-						return err
-					}
-					p.Value = cv
+// RadiusSearchPage returns the Points found in the index inside radius, up to count starting after cursor
+func (idx *Indexer) RadiusSearchPage(lat, lng, radius float64, cursor []byte, count int) ([]storage.DataPoint, []byte, error) {
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	acap := s2.CapFromCenterArea(center, storage.S2RadialAreaMeters(radius))
+	coverer := &s2.RegionCoverer{MaxCells: 8}
+	cu := coverer.Covering(acap)
 
-					res = append(res, p)
-				}
+	res, next, err := idx.searchCells("G", cu, acap.ContainsPoint, storage.MinGeoTime, storage.MaxGeoTime, cursor, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	setDistances(center, res)
+	return res, next, nil
+}
+
+// NearestSearch returns the k nearest Points from lat lng sorted by distance,
+// up to maxDistance in meters, 0 for no limit
+// the search starts from the cell containing lat lng and its neighbors, growing to the parent cells
+// until k Points are found closer than the distance guaranteed to be covered
+func (idx *Indexer) NearestSearch(lat, lng float64, k int, maxDistance float64) ([]storage.DataPoint, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	leaf := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))
+	contains := func(p s2.Point) bool {
+		return maxDistance <= 0 || storage.S2DistanceMeters(center, p) <= maxDistance
+	}
+
+	for level := nearestStartLevel; level >= 0; level-- {
+		var cu s2.CellUnion
+		// any point closer than covered is inside the cell or its neighbors
+		covered := storage.S2AngleMeters(s2.MinWidthMetric.Value(level))
+		if level > 0 {
+			c := leaf.Parent(level)
+			cu = append(c.AllNeighbors(level), c)
+		} else {
+			// last resort, the whole world
+			for f := 0; f < 6; f++ {
+				cu = append(cu, s2.CellIDFromFace(f))
 			}
-			return nil
-		})
+			covered = math.Inf(1)
+		}
+		cu.Normalize()
+
+		res, _, err := idx.searchCells("G", cu, contains, storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
 		if err != nil {
 			return nil, err
 		}
+		setDistances(center, res)
+		sort.Slice(res, func(i, j int) bool { return res[i].Distance < res[j].Distance })
+
+		if len(res) >= k && res[k-1].Distance <= covered {
+			return res[:k], nil
+		}
+
+		// no need to grow further
+		if level == 0 || (maxDistance > 0 && maxDistance <= covered) {
+			if len(res) > k {
+				res = res[:k]
+			}
+			return res, nil
+		}
 	}
-	return res, nil
+	return nil, nil
 }
 
-// RadiusSearch returns the Points found in the index inside radius (no data)
-func (idx *Indexer) RadiusSearch(lat, lng, radius float64) ([]storage.DataPoint, error) {
+// setDistances sets the distance in meters to center
+func setDistances(center s2.Point, dps []storage.DataPoint) {
+	for i, dp := range dps {
+		p := s2.PointFromLatLng(s2.LatLngFromDegrees(dp.Lat, dp.Lng))
+		dps[i].Distance = storage.S2DistanceMeters(center, p)
+	}
+}
+
+// PolygonSearch returns the Points found in the index inside the polygon, up to count starting after cursor
+func (idx *Indexer) PolygonSearch(p *s2.Polygon, cursor []byte, count int) ([]storage.DataPoint, []byte, error) {
+	coverer := &s2.RegionCoverer{MaxCells: 16}
+	cu := coverer.Covering(p)
+
+	return idx.searchCells("G", cu, p.ContainsPoint, storage.MinGeoTime, storage.MaxGeoTime, cursor, count)
+}
+
+// RectHistorySearch returns all the Points that were in the rect between start and end
+// up to count starting after cursor
+func (idx *Indexer) RectHistorySearch(urlat, urlng, bllat, bllng float64, start, end time.Time, cursor []byte, count int) ([]storage.DataPoint, []byte, error) {
+	rect := s2.RectFromLatLng(s2.LatLngFromDegrees(bllat, bllng))
+	rect = rect.AddPoint(s2.LatLngFromDegrees(urlat, urlng))
+	coverer := &s2.RegionCoverer{MaxCells: 8}
+	cu := coverer.Covering(rect)
+
+	return idx.searchCells("H", cu, rect.ContainsPoint, start, end, cursor, count)
+}
+
+// RadiusHistorySearch returns all the Points that were inside radius between start and end
+// up to count starting after cursor
+func (idx *Indexer) RadiusHistorySearch(lat, lng, radius float64, start, end time.Time, cursor []byte, count int) ([]storage.DataPoint, []byte, error) {
 	center := s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lng))
 	acap := s2.CapFromCenterArea(center, storage.S2RadialAreaMeters(radius))
 	coverer := &s2.RegionCoverer{MaxCells: 8}
 	cu := coverer.Covering(acap)
+
+	res, next, err := idx.searchCells("H", cu, acap.ContainsPoint, start, end, cursor, count)
+	if err != nil {
+		return nil, nil, err
+	}
+	setDistances(center, res)
+	return res, next, nil
+}
+
+// searchCells iterates the geo index (G or H) over the cells in cu,
+// returning the Points matching contains between start and end
+// cells are sorted so the keys are always visited in order, making the last key a valid cursor
+func (idx *Indexer) searchCells(index string, cu s2.CellUnion, contains func(s2.Point) bool, start, end time.Time, cursor []byte, count int) ([]storage.DataPoint, []byte, error) {
+	prefix := []byte(storage.Prefix + index)
+	if cursor != nil && (len(cursor) < len(prefix)+8+8 || !bytes.HasPrefix(cursor, prefix)) {
+		return nil, nil, storage.ErrInvalidCursor
+	}
+
 	var res []storage.DataPoint
+	var last, next []byte
 
-	for _, c := range cu {
-		// a key prefix+"G"+cellid
-		start := make([]byte, len(storage.Prefix)+1+8)
-		copy(start, storage.Prefix+"G")
-		copy(start[len(storage.Prefix)+1:], storage.Uint64tob(uint64(c.RangeMin())))
-		stop := make([]byte, len(storage.Prefix)+1+8)
-		copy(stop, storage.Prefix+"G")
-		copy(stop[len(storage.Prefix)+1:], storage.Uint64tob(uint64(c.RangeMax())))
+	err := idx.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = true
+		it := txn.NewIterator(opts)
+		defer it.Close()
 
-		err := idx.View(func(txn *badger.Txn) error {
-			opts := badger.DefaultIteratorOptions
-			opts.PrefetchValues = true
-			it := txn.NewIterator(opts)
-			defer it.Close()
-			for it.Seek(start); it.Valid(); it.Next() {
+		for _, c := range cu {
+			// a key prefix+index+cellid
+			seek := make([]byte, len(prefix)+8)
+			copy(seek, prefix)
+			copy(seek[len(prefix):], storage.Uint64tob(uint64(c.RangeMin())))
+			stop := make([]byte, len(prefix)+8)
+			copy(stop, prefix)
+			copy(stop[len(prefix):], storage.Uint64tob(uint64(c.RangeMax())))
+
+			if cursor != nil {
+				// this cell was already visited
+				if bytes.Compare(cursor[:len(stop)], stop) > 0 {
+					continue
+				}
+				if bytes.Compare(cursor, seek) > 0 {
+					seek = cursor
+				}
+			}
+
+			for it.Seek(seek); it.ValidForPrefix(prefix); it.Next() {
 				item := it.Item()
 				k := item.Key()
-				if bytes.Compare(k, stop) > 0 {
+				if bytes.Compare(k[:len(stop)], stop) > 0 {
 					break
 				}
+				if cursor != nil && bytes.Equal(k, cursor) {
+					continue
+				}
 				ck := item.KeyCopy(nil)
-				c, t, rk, _ := storage.ReadPointKey(ck)
-
-				if acap.ContainsPoint(c.Point()) {
-					p := storage.DataPoint{
-						Lat:  c.LatLng().Lat.Degrees(),
-						Lng:  c.LatLng().Lng.Degrees(),
-						Time: t,
-						Key:  rk,
-					}
-					cv, err := item.ValueCopy(nil)
+				c, t, rk, err := storage.ReadPointKey(ck)
+				if err != nil {
+					return err
+				}
+
+				if t.Before(start) || t.After(end) || !contains(c.Point()) {
+					continue
+				}
+
+				// there are more entries, the last returned one is the cursor
+				if count > 0 && len(res) >= count {
+					next = last
+					return nil
+				}
+
+				p := storage.DataPoint{
+					Lat:  c.LatLng().Lat.Degrees(),
+					Lng:  c.LatLng().Lng.Degrees(),
+					Time: t,
+					Key:  rk,
+				}
+				cv, err := item.ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+				p.Value = cv
+
+				// historical points come with their metadata
+				if index == "H" {
+					p.Meta, err = getMeta(txn, storage.MetaCellKey(rk, t, c))
 					if err != nil {
 						return err
 					}
-					p.Value = cv
-
-					res = append(res, p)
 				}
+
+				res = append(res, p)
+				last = ck
 			}
-			return nil
-		})
-		if err != nil {
-			return nil, err
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	return res, nil
+	return res, next, nil
 }
 
 func (idx *Indexer) Begin() storage.Tx {
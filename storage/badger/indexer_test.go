@@ -1,20 +1,24 @@
 package badger
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/dgraph-io/badger/v2"
+	"github.com/golang/geo/s2"
 	"github.com/stretchr/testify/require"
+
+	"github.com/akhenakh/geottn/storage"
 )
 
-func openStore(t *testing.T) (*badger.DB, func()) {
+func openStore(t testing.TB) (*badger.DB, func()) {
 	dir, err := ioutil.TempDir("", "badger")
 	require.NoError(t, err)
 
-	opt := badger.DefaultOptions(dir)
+	opt := badger.DefaultOptions(dir).WithLogger(nil)
 
 	db, err := badger.Open(opt)
 	require.NoError(t, err)
@@ -113,3 +117,582 @@ func TestGetAll(t *testing.T) {
 	require.InDelta(t, 48.802, dp.Lat, 0.002)
 	require.InDelta(t, 2.201, dp.Lng, 0.002)
 }
+
+func TestGetRange(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	k := "KEY"
+	for i := 0; i < 10; i++ {
+		err := idx.Store(k, []byte{byte(i)}, 48.8, 2.2, ts.Add(time.Duration(i)*time.Hour))
+		require.NoError(t, err)
+	}
+
+	// another device with a similar key should not be returned
+	err := idx.Store("KEY2", []byte("VALUE"), 48.8, 2.2, ts.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	res, err := idx.GetRange(k, ts.Add(2*time.Hour), ts.Add(5*time.Hour), 0)
+	require.NoError(t, err)
+	require.Len(t, res, 4)
+	// most recent first
+	require.Equal(t, ts.Add(5*time.Hour), res[0].Time)
+	require.Equal(t, []byte{5}, res[0].Value)
+	require.Equal(t, ts.Add(2*time.Hour), res[3].Time)
+
+	res, err = idx.GetRange(k, ts.Add(2*time.Hour), ts.Add(5*time.Hour), 2)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+	require.Equal(t, ts.Add(4*time.Hour), res[1].Time)
+
+	res, err = idx.GetRange(k, ts.Add(-2*time.Hour), ts.Add(-1*time.Hour), 0)
+	require.NoError(t, err)
+	require.Len(t, res, 0)
+}
+
+func TestPagination(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+	ts := time.Now().UTC()
+	k := "KEY"
+	for i := 0; i < 5; i++ {
+		err := idx.Store(k, []byte{byte(i)}, 48.8, 2.2, ts.Add(time.Duration(i)*time.Second))
+		require.NoError(t, err)
+		err = idx.Store(fmt.Sprintf("DEV%d", i), []byte{byte(i)}, 48.8+float64(i)*0.001, 2.2, ts)
+		require.NoError(t, err)
+	}
+
+	var all []storage.DataPoint
+	var cursor []byte
+	for {
+		res, next, err := idx.GetRangePage(k, storage.MinGeoTime, storage.MaxGeoTime, cursor, 2)
+		require.NoError(t, err)
+		all = append(all, res...)
+		if next == nil {
+			break
+		}
+		require.Len(t, res, 2)
+		cursor = next
+	}
+	require.Len(t, all, 5)
+	for i, dp := range all {
+		require.Equal(t, []byte{byte(4 - i)}, dp.Value)
+	}
+
+	all = nil
+	cursor = nil
+	for {
+		res, next, err := idx.GetRangePageAsc(k, storage.MinGeoTime, storage.MaxGeoTime, cursor, 2)
+		require.NoError(t, err)
+		all = append(all, res...)
+		if next == nil {
+			break
+		}
+		require.Len(t, res, 2)
+		cursor = next
+	}
+	require.Len(t, all, 5)
+	for i, dp := range all {
+		require.Equal(t, []byte{byte(i)}, dp.Value)
+	}
+
+	res, next, err := idx.GetRangePageAsc(k, ts.Add(time.Second), ts.Add(3*time.Second), nil, 0)
+	require.NoError(t, err)
+	require.Nil(t, next)
+	require.Len(t, res, 3)
+	require.Equal(t, []byte{1}, res[0].Value)
+	require.Equal(t, []byte{3}, res[2].Value)
+
+	seen := make(map[string]bool)
+	cursor = nil
+	for {
+		res, next, err := idx.RadiusSearchPage(48.8, 2.2, 10000, cursor, 2)
+		require.NoError(t, err)
+		for _, dp := range res {
+			require.False(t, seen[dp.Key])
+			seen[dp.Key] = true
+		}
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	// 5 devices + KEY
+	require.Len(t, seen, 6)
+
+	res, next, err = idx.RectSearchPage(48.83, 2.56, 48.62, 2.13, nil, 6)
+	require.NoError(t, err)
+	require.Len(t, res, 6)
+	require.Nil(t, next)
+
+	_, _, err = idx.RectSearchPage(48.83, 2.56, 48.62, 2.13, []byte("TTDKEY"), 6)
+	require.Equal(t, storage.ErrInvalidCursor, err)
+}
+
+func TestRetention(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB:              bdb,
+		Retention:       time.Hour,
+		DeviceRetention: map[string]time.Duration{"SHORT": 2 * time.Second},
+	}
+	ts := time.Now().UTC()
+	k := "KEY"
+
+	// too old to be stored
+	err := idx.Store(k, []byte("VALUE"), 48.8, 2.2, ts.Add(-2*time.Hour))
+	require.Equal(t, storage.ErrExpired, err)
+	res, err := idx.GetAll(k, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 0)
+
+	err = idx.Store(k, []byte("VALUE"), 48.8, 2.2, ts.Add(-30*time.Minute))
+	require.NoError(t, err)
+	err = idx.Store(k, []byte("VALUE2"), 48.8, 2.2, ts)
+	require.NoError(t, err)
+	res, err = idx.GetAll(k, 0)
+	require.NoError(t, err)
+	require.Len(t, res, 2)
+
+	// L is following the most recent D
+	err = idx.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(storage.ListKey(k))
+		require.NoError(t, err)
+		require.Equal(t, uint64(ts.Add(time.Hour).Unix()), item.ExpiresAt())
+
+		item, err = txn.Get(storage.DataKey(k, ts, 48.8, 2.2))
+		require.NoError(t, err)
+		require.Equal(t, uint64(ts.Add(time.Hour).Unix()), item.ExpiresAt())
+		return nil
+	})
+	require.NoError(t, err)
+
+	// all entries for SHORT are expiring together
+	err = idx.Store("SHORT", []byte("VALUE"), 48.8, 2.2, ts)
+	require.NoError(t, err)
+	keys, err := idx.Keys()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+
+	time.Sleep(3 * time.Second)
+
+	keys, err = idx.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{k}, keys)
+	res, err = idx.GetAll("SHORT", 0)
+	require.NoError(t, err)
+	require.Len(t, res, 0)
+	res, err = idx.RadiusSearch(48.8, 2.2, 1000)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, k, res[0].Key)
+}
+
+func TestRetentionDisabled(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB:              bdb,
+		DeviceRetention: map[string]time.Duration{"KEY": 2 * time.Second},
+	}
+	ts := time.Now().UTC()
+	err := idx.Store("KEY", []byte("VALUE"), 48.8, 2.2, ts)
+	require.NoError(t, err)
+
+	// the retention is switched off, the new point and L never expire
+	idx.DeviceRetention = nil
+	err = idx.Store("KEY", []byte("VALUE2"), 48.8, 2.2, ts.Add(time.Second))
+	require.NoError(t, err)
+	err = idx.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(storage.ListKey("KEY"))
+		require.NoError(t, err)
+		require.Equal(t, uint64(0), item.ExpiresAt())
+		return nil
+	})
+	require.NoError(t, err)
+
+	time.Sleep(3 * time.Second)
+
+	keys, err := idx.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"KEY"}, keys)
+
+	// the G entry of the previous point is replaced
+	err = idx.Store("KEY", []byte("VALUE3"), 48.8, 2.2, ts.Add(2*time.Second))
+	require.NoError(t, err)
+	res, err := idx.RadiusSearch(48.8, 2.2, 1000)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, "VALUE3", string(res[0].Value))
+}
+
+func TestDelete(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+	ts := time.Now().UTC()
+	for i := 0; i < 3; i++ {
+		err := idx.Store("KEY", []byte("VALUE"), 48.8, 2.2+float64(i)*0.01, ts.Add(time.Duration(i)*time.Second))
+		require.NoError(t, err)
+	}
+	err := idx.Store("KEY2", []byte("VALUE"), 48.8, 2.2, ts)
+	require.NoError(t, err)
+
+	err = idx.Delete("KEY")
+	require.NoError(t, err)
+
+	res, err := idx.GetAll("KEY", 0)
+	require.NoError(t, err)
+	require.Len(t, res, 0)
+
+	keys, err := idx.Keys()
+	require.NoError(t, err)
+	require.Equal(t, []string{"KEY2"}, keys)
+
+	res, err = idx.RadiusSearch(48.8, 2.2, 10000)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+	require.Equal(t, "KEY2", res[0].Key)
+
+	// deleting a missing key is fine
+	err = idx.Delete("KEY")
+	require.NoError(t, err)
+}
+
+func TestHistorySearch(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+
+	// KEY went through the area at 10:00 then moved away
+	err := idx.Store("KEY", []byte("VALUE"), 48.8, 2.2, ts)
+	require.NoError(t, err)
+	err = idx.Store("KEY", []byte("VALUE"), 44.8, 2.2, ts.Add(time.Hour))
+	require.NoError(t, err)
+
+	// KEY2 was in the area at 12:00
+	err = idx.Store("KEY2", []byte("VALUE"), 48.801, 2.201, ts.Add(2*time.Hour))
+	require.NoError(t, err)
+
+	// only KEY2 is currently in the area
+	dps, err := idx.RadiusSearch(48.8, 2.2, 10000)
+	require.NoError(t, err)
+	require.Len(t, dps, 1)
+	require.Equal(t, "KEY2", dps[0].Key)
+
+	dps, next, err := idx.RadiusHistorySearch(48.8, 2.2, 10000, ts.Add(-time.Minute), ts.Add(time.Minute), nil, 0)
+	require.NoError(t, err)
+	require.Nil(t, next)
+	require.Len(t, dps, 1)
+	require.Equal(t, "KEY", dps[0].Key)
+	require.Equal(t, ts, dps[0].Time)
+
+	dps, _, err = idx.RectHistorySearch(48.83, 2.56, 48.62, 2.13, storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 2)
+
+	dps, next, err = idx.RectHistorySearch(48.83, 2.56, 48.62, 2.13, storage.MinGeoTime, storage.MaxGeoTime, nil, 1)
+	require.NoError(t, err)
+	require.Len(t, dps, 1)
+	require.NotNil(t, next)
+
+	// deleting the device is removing its history
+	err = idx.Delete("KEY")
+	require.NoError(t, err)
+	dps, _, err = idx.RectHistorySearch(48.83, 2.56, 48.62, 2.13, storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 1)
+	require.Equal(t, "KEY2", dps[0].Key)
+}
+
+func TestPolygonSearch(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+	ts := time.Now().UTC()
+	err := idx.Store("INSIDE", []byte("VALUE"), 48.2, 2.2, ts)
+	require.NoError(t, err)
+	err = idx.Store("HOLE", []byte("VALUE"), 48.5, 2.5, ts)
+	require.NoError(t, err)
+	err = idx.Store("OUTSIDE", []byte("VALUE"), 47.5, 2.5, ts)
+	require.NoError(t, err)
+
+	p, err := storage.NewPolygon(
+		[]s2.LatLng{
+			s2.LatLngFromDegrees(48.0, 2.0),
+			s2.LatLngFromDegrees(48.0, 3.0),
+			s2.LatLngFromDegrees(49.0, 3.0),
+			s2.LatLngFromDegrees(49.0, 2.0),
+		},
+		[]s2.LatLng{
+			s2.LatLngFromDegrees(48.4, 2.4),
+			s2.LatLngFromDegrees(48.4, 2.6),
+			s2.LatLngFromDegrees(48.6, 2.6),
+			s2.LatLngFromDegrees(48.6, 2.4),
+		},
+	)
+	require.NoError(t, err)
+
+	dps, next, err := idx.PolygonSearch(p, nil, 0)
+	require.NoError(t, err)
+	require.Nil(t, next)
+	require.Len(t, dps, 1)
+	require.Equal(t, "INSIDE", dps[0].Key)
+}
+
+func TestNearestSearch(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+	ts := time.Now().UTC()
+	// devices every ~1.1km going north, plus a far away one
+	for i := 0; i < 10; i++ {
+		err := idx.Store(fmt.Sprintf("DEV%d", i), []byte("VALUE"), 48.8+float64(i)*0.01, 2.2, ts)
+		require.NoError(t, err)
+	}
+	err := idx.Store("FAR", []byte("VALUE"), -33.8, 151.2, ts)
+	require.NoError(t, err)
+
+	dps, err := idx.NearestSearch(48.8, 2.2, 3, 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 3)
+	for i, dp := range dps {
+		require.Equal(t, fmt.Sprintf("DEV%d", i), dp.Key)
+		require.InDelta(t, float64(i)*1112, dp.Distance, 10)
+	}
+
+	// starting from the other end
+	dps, err = idx.NearestSearch(48.895, 2.2, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 2)
+	require.Equal(t, "DEV9", dps[0].Key)
+	require.Equal(t, "DEV8", dps[1].Key)
+
+	// the whole world is searched
+	dps, err = idx.NearestSearch(48.8, 2.2, 20, 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 11)
+	require.Equal(t, "FAR", dps[10].Key)
+
+	// limited by distance
+	dps, err = idx.NearestSearch(48.8, 2.2, 20, 2500)
+	require.NoError(t, err)
+	require.Len(t, dps, 3)
+}
+
+func TestStoreOlder(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	err := idx.Store("KEY", []byte("RECENT"), 48.8, 2.2, ts)
+	require.NoError(t, err)
+
+	// an older point, eg imported history, does not replace the current position
+	err = idx.Store("KEY", []byte("OLD"), 44.8, 2.2, ts.Add(-time.Hour))
+	require.NoError(t, err)
+
+	dp, err := idx.Get("KEY")
+	require.NoError(t, err)
+	require.Equal(t, []byte("RECENT"), dp.Value)
+
+	dps, err := idx.RadiusSearch(44.8, 2.2, 10000)
+	require.NoError(t, err)
+	require.Len(t, dps, 0)
+
+	dps, err = idx.RadiusSearch(48.8, 2.2, 10000)
+	require.NoError(t, err)
+	require.Len(t, dps, 1)
+
+	dps, _, err = idx.RadiusHistorySearch(44.8, 2.2, 10000, storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 1)
+
+	dps, err = idx.GetAll("KEY", 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 2)
+}
+
+func TestStoreTxTooBig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bdb, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil).WithMaxTableSize(1 << 16))
+	require.NoError(t, err)
+	defer bdb.Close()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+
+	tx := idx.Begin()
+	defer tx.Discard()
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		err = idx.StoreTx(tx, "KEY", nil, 48.8, 2.2, ts.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			break
+		}
+	}
+	require.Equal(t, storage.ErrTxTooBig, err)
+}
+
+func TestDeleteTxTooBig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bdb, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil).WithMaxTableSize(1 << 16))
+	require.NoError(t, err)
+	defer bdb.Close()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 1000; i++ {
+		err = idx.Store("KEY", nil, 48.8, 2.2, ts.Add(time.Duration(i)*time.Second))
+		require.NoError(t, err)
+	}
+
+	err = idx.Delete("KEY")
+	require.NoError(t, err)
+
+	dps, err := idx.GetAll("KEY", 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 0)
+
+	keys, err := idx.Keys()
+	require.NoError(t, err)
+	require.Len(t, keys, 0)
+}
+
+// BenchmarkStore commits a transaction per point
+func BenchmarkStore(b *testing.B) {
+	bdb, clean := openStore(b)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := fmt.Sprintf("KEY%d", i%100)
+		if err := idx.Store(k, []byte("VALUE"), 48.8, 2.2, ts.Add(time.Duration(i)*time.Second)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkStoreTxBatch commits a transaction every 1000 points
+func BenchmarkStoreTxBatch(b *testing.B) {
+	bdb, clean := openStore(b)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	b.ResetTimer()
+	tx := idx.Begin()
+	for i := 0; i < b.N; i++ {
+		k := fmt.Sprintf("KEY%d", i%100)
+		if err := idx.StoreTx(tx, k, []byte("VALUE"), 48.8, 2.2, ts.Add(time.Duration(i)*time.Second)); err != nil {
+			b.Fatal(err)
+		}
+		if i%1000 == 999 {
+			if err := tx.Commit(); err != nil {
+				b.Fatal(err)
+			}
+			tx = idx.Begin()
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	tx := idx.Begin()
+	require.NoError(t, idx.StoreTx(tx, "KEY", nil, 48.8, 2.2, ts))
+	require.NoError(t, idx.StoreMetaTx(tx, "KEY", 48.8, 2.2, ts, &storage.Metadata{TimeSource: storage.TimeSourceGateway}))
+	require.NoError(t, idx.StoreTx(tx, "KEY", nil, 48.8, 2.2, ts.Add(time.Minute)))
+	require.NoError(t, tx.Commit())
+
+	dps, err := idx.GetAll("KEY", 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 2)
+	require.Nil(t, dps[0].Meta)
+	require.NotNil(t, dps[1].Meta)
+	require.Equal(t, storage.TimeSourceGateway, dps[1].Meta.TimeSource)
+
+	require.NoError(t, idx.Delete("KEY"))
+	err = idx.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(storage.MetaKey("KEY", ts, 48.8, 2.2))
+		return err
+	})
+	require.Equal(t, badger.ErrKeyNotFound, err)
+}
+
+func TestHistorySearchMeta(t *testing.T) {
+	bdb, clean := openStore(t)
+	defer clean()
+
+	idx := &Indexer{
+		DB: bdb,
+	}
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	m := &storage.Metadata{Gateways: []storage.GatewayMetadata{{ID: "gw1", RSSI: -100, SNR: 5}}}
+	tx := idx.Begin()
+	require.NoError(t, idx.StoreTx(tx, "KEY", nil, 48.8, 2.2, ts))
+	require.NoError(t, idx.StoreMetaTx(tx, "KEY", 48.8, 2.2, ts, m))
+	require.NoError(t, tx.Commit())
+
+	dps, _, err := idx.RectHistorySearch(48.83, 2.56, 48.62, 2.13, storage.MinGeoTime, storage.MaxGeoTime, nil, 0)
+	require.NoError(t, err)
+	require.Len(t, dps, 1)
+	require.Equal(t, m, dps[0].Meta)
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"math"
 	"time"
 
@@ -11,15 +12,39 @@ import (
 
 const Prefix = "TT"
 
+var (
+	// ErrInvalidCursor is returned when a cursor does not belong to the query
+	ErrInvalidCursor = errors.New("invalid cursor")
+
+	// ErrTxTooBig is returned when a transaction can't hold more writes
+	ErrTxTooBig = errors.New("transaction too big")
+
+	// ErrExpired is returned when storing a point already past the retention
+	ErrExpired = errors.New("data point past the retention")
+)
+
 type Indexer interface {
 	Store(k string, v []byte, lat, lng float64, t time.Time) error
 	StoreTx(tx Tx, k string, v []byte, lat, lng float64, t time.Time) error
+	StoreMetaTx(tx Tx, k string, lat, lng float64, t time.Time, m *Metadata) error
 	Get(k string) (*DataPoint, error)
 	Keys() ([]string, error)
+	Delete(k string) error
 	GetAll(k string, count int) ([]DataPoint, error)
+	GetRange(k string, start, end time.Time, count int) ([]DataPoint, error)
+	GetRangePage(k string, start, end time.Time, cursor []byte, count int) ([]DataPoint, []byte, error)
+	GetRangePageAsc(k string, start, end time.Time, cursor []byte, count int) ([]DataPoint, []byte, error)
 	RadiusSearch(lat, lng, radius float64) ([]DataPoint, error)
+	RadiusSearchPage(lat, lng, radius float64, cursor []byte, count int) ([]DataPoint, []byte, error)
 	RectSearch(urlat, urlng, bllat, bllng float64) ([]DataPoint, error)
+	RectSearchPage(urlat, urlng, bllat, bllng float64, cursor []byte, count int) ([]DataPoint, []byte, error)
+	NearestSearch(lat, lng float64, k int, maxDistance float64) ([]DataPoint, error)
+	PolygonSearch(p *s2.Polygon, cursor []byte, count int) ([]DataPoint, []byte, error)
+	RadiusHistorySearch(lat, lng, radius float64, start, end time.Time, cursor []byte, count int) ([]DataPoint, []byte, error)
+	RectHistorySearch(urlat, urlng, bllat, bllng float64, start, end time.Time, cursor []byte, count int) ([]DataPoint, []byte, error)
 	Begin() Tx
+
+	FenceIndexer
 }
 
 type Tx interface {
@@ -32,29 +57,47 @@ type DataPoint struct {
 	Key      string
 	Value    []byte
 	Time     time.Time
+
+	// Distance in meters from the queried point, for searches around a point
+	Distance float64
+
+	// Meta is the optional metadata stored with the point, only returned when querying a key or the history
+	Meta *Metadata
 }
 
 func DataKey(k string, t time.Time, lat, lng float64) []byte {
 	// the data key Prefix+"D"+k+#+time+s2
+	return dataKey("D", k, t, s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)))
+}
+
+func dataKey(index string, k string, t time.Time, c s2.CellID) []byte {
 	dk := make([]byte, len(Prefix)+1+len(k)+1+8+8)
-	copy(dk, Prefix+"D")
+	copy(dk, Prefix+index)
 	copy(dk[len(Prefix)+1:], k)
 	dk[len(Prefix)+1+len(k)] = '#'
 	// using reverse timestamp
 	ts := int64tob(math.MaxInt64 - t.UnixNano())
 	copy(dk[len(Prefix)+1+len(k)+1:], ts)
-
-	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))
 	copy(dk[len(Prefix)+1+len(k)+1+8:], itob(uint64(c)))
 	return dk
 }
 
 // PointKey returns the key generated for a position + id
 func PointKey(lat, lng float64, t time.Time, k string) []byte {
-	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))
 	// a key Prefix+"G"+cellid+ts+k
+	return geoKey("G", lat, lng, t, k)
+}
+
+// HistoryKey returns the key generated for a position + id in the historical geo index
+func HistoryKey(lat, lng float64, t time.Time, k string) []byte {
+	// a key Prefix+"H"+cellid+ts+k
+	return geoKey("H", lat, lng, t, k)
+}
+
+func geoKey(index string, lat, lng float64, t time.Time, k string) []byte {
+	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng))
 	gk := make([]byte, len(Prefix)+1+8+8+len(k))
-	copy(gk, Prefix+"G")
+	copy(gk, Prefix+index)
 	copy(gk[len(Prefix)+1:], itob(uint64(c)))
 	// using reverse timestamp
 	ts := int64tob(math.MaxInt64 - t.UnixNano())
@@ -69,7 +112,7 @@ func ListKey(k string) []byte {
 	return []byte(Prefix + "L" + k)
 }
 
-// ReadPointKey returns cell, time, key
+// ReadPointKey returns cell, time, key, for both PointKey and HistoryKey
 func ReadPointKey(pk []byte) (s2.CellID, time.Time, string, error) {
 	buf := bytes.NewBuffer(pk[len(Prefix)+1:])
 	var c s2.CellID
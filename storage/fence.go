@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// FencePrefix is the prefix for all the geofences related keys
+const FencePrefix = "TF"
+
+const (
+	FenceEnter FenceEventType = iota + 1
+	FenceExit
+)
+
+var (
+	// ErrNotFound is returned when querying a missing entry
+	ErrNotFound = errors.New("not found")
+
+	// ErrInvalidFence is returned when a fence is neither a circle nor a polygon
+	ErrInvalidFence = errors.New("invalid fence")
+)
+
+type FenceIndexer interface {
+	StoreFence(f *Geofence) error
+	GetFence(id string) (*Geofence, error)
+	DeleteFence(id string) error
+	Fences() ([]Geofence, error)
+	StoreFenceEventTx(tx Tx, e *GeofenceEvent) error
+	FenceEvents(id string, start, end time.Time, cursor []byte, count int) ([]GeofenceEvent, []byte, error)
+	DeviceFenceEvents(k string, start, end time.Time, cursor []byte, count int) ([]GeofenceEvent, []byte, error)
+}
+
+// Geofence is a named area, a circle or a polygon if Rings is set
+type Geofence struct {
+	ID   string
+	Name string
+
+	// circle center and radius in meters
+	Lat, Lng, Radius float64
+
+	// polygon rings, see NewPolygon
+	Rings [][]s2.LatLng
+}
+
+type FenceEventType uint8
+
+// GeofenceEvent is recorded when the device Key crosses the fence boundary
+type GeofenceEvent struct {
+	FenceID  string
+	Key      string
+	Type     FenceEventType
+	Time     time.Time
+	Lat, Lng float64
+}
+
+// Region returns the s2 region covered by the fence
+func (f *Geofence) Region() (s2.Region, error) {
+	if len(f.Rings) > 0 {
+		return NewPolygon(f.Rings...)
+	}
+	if f.Radius <= 0 {
+		return nil, ErrInvalidFence
+	}
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(f.Lat, f.Lng))
+	return s2.CapFromCenterArea(center, S2RadialAreaMeters(f.Radius)), nil
+}
+
+func (t FenceEventType) String() string {
+	switch t {
+	case FenceEnter:
+		return "enter"
+	case FenceExit:
+		return "exit"
+	}
+	return "unknown"
+}
+
+// FenceKey returns the key used to store a fence
+func FenceKey(id string) []byte {
+	// a key FencePrefix+"F"+id
+	return []byte(FencePrefix + "F" + id)
+}
+
+// FenceEventKey returns the key used to list the events of a fence
+func FenceEventKey(id string, t time.Time, k string) []byte {
+	// a key FencePrefix+"E"+id+#+time+k
+	return eventKey("E", id, t, k)
+}
+
+// DeviceFenceEventKey returns the key used to list the fence events of a device
+func DeviceFenceEventKey(k string, t time.Time, id string) []byte {
+	// a key FencePrefix+"V"+k+#+time+id
+	return eventKey("V", k, t, id)
+}
+
+func eventKey(index, id string, t time.Time, k string) []byte {
+	ek := make([]byte, len(FencePrefix)+1+len(id)+1+8+len(k))
+	copy(ek, FencePrefix+index)
+	copy(ek[len(FencePrefix)+1:], id)
+	ek[len(FencePrefix)+1+len(id)] = '#'
+	// using reverse timestamp
+	ts := int64tob(math.MaxInt64 - t.UnixNano())
+	copy(ek[len(FencePrefix)+1+len(id)+1:], ts)
+	copy(ek[len(FencePrefix)+1+len(id)+1+8:], k)
+	return ek
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/golang/geo/s2"
+)
+
+// time sources of a data point
+const (
+	// TimeSourceGateway is the time the uplink was received by a gateway
+	TimeSourceGateway = "gateway"
+
+	// TimeSourceNetwork is the time the uplink was received by the network server
+	TimeSourceNetwork = "network"
+
+	// TimeSourceLocal is the time the uplink was received by geottn
+	TimeSourceLocal = "local"
+)
+
+// Metadata is stored alongside a data point
+type Metadata struct {
+	// TimeSource is where the point time comes from
+	TimeSource string `json:"time_source,omitempty"`
+
+	// FCnt is the uplink frame counter
+	FCnt uint32 `json:"fcnt,omitempty"`
+
+	// DataRate is the LoRa data rate, eg SF7BW125
+	DataRate string `json:"data_rate,omitempty"`
+
+	// Frequency in MHz
+	Frequency float32 `json:"frequency,omitempty"`
+
+	// Gateways receiving the uplink
+	Gateways []GatewayMetadata `json:"gateways,omitempty"`
+
+	// Estimated is set when the position was estimated from the gateways, within Accuracy meters
+	Estimated bool    `json:"estimated,omitempty"`
+	Accuracy  float64 `json:"accuracy,omitempty"`
+
+	// Decoder is the name of the payload decoder, empty for the default one
+	Decoder string `json:"decoder,omitempty"`
+}
+
+// GatewayMetadata is the reception of an uplink by a gateway
+type GatewayMetadata struct {
+	ID string `json:"id"`
+
+	// RSSI in dBm, SNR in dB
+	RSSI float32 `json:"rssi"`
+	SNR  float32 `json:"snr"`
+
+	// gateway location if known
+	Lat float64 `json:"lat,omitempty"`
+	Lng float64 `json:"lng,omitempty"`
+	Alt int32   `json:"alt,omitempty"`
+}
+
+// MetaKey returns the key used to store the metadata of a data point, see DataKey
+func MetaKey(k string, t time.Time, lat, lng float64) []byte {
+	return MetaCellKey(k, t, s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lng)))
+}
+
+// MetaCellKey returns the metadata key for the data point in the leaf cell c
+func MetaCellKey(k string, t time.Time, c s2.CellID) []byte {
+	// a key Prefix+"M"+k+#+time+s2
+	return dataKey("M", k, t, c)
+}
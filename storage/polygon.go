@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/golang/geo/s2"
+)
+
+// ErrInvalidPolygon is returned when a ring can't be used to build a polygon
+var ErrInvalidPolygon = errors.New("invalid polygon")
+
+// NewPolygon returns a polygon built from rings of lat lng
+// shells and holes are found by their nesting, so multiple shells can be passed to build a multipolygon
+// the rings orientation does not matter and the closing vertex is optional
+func NewPolygon(rings ...[]s2.LatLng) (*s2.Polygon, error) {
+	if len(rings) == 0 {
+		return nil, ErrInvalidPolygon
+	}
+
+	loops := make([]*s2.Loop, len(rings))
+	for i, ring := range rings {
+		// closed ring
+		if len(ring) > 1 && ring[0] == ring[len(ring)-1] {
+			ring = ring[:len(ring)-1]
+		}
+		if len(ring) < 3 {
+			return nil, ErrInvalidPolygon
+		}
+
+		pts := make([]s2.Point, len(ring))
+		for j, ll := range ring {
+			pts[j] = s2.PointFromLatLng(ll)
+		}
+
+		l := s2.LoopFromPoints(pts)
+		if err := l.Validate(); err != nil {
+			return nil, err
+		}
+		// a loop contains the area on its left, always use the smallest one
+		l.Normalize()
+		loops[i] = l
+	}
+
+	return s2.PolygonFromLoops(loops), nil
+}
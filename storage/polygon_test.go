@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/golang/geo/s2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPolygon(t *testing.T) {
+	shell := []s2.LatLng{
+		s2.LatLngFromDegrees(48.0, 2.0),
+		s2.LatLngFromDegrees(48.0, 3.0),
+		s2.LatLngFromDegrees(49.0, 3.0),
+		s2.LatLngFromDegrees(49.0, 2.0),
+		s2.LatLngFromDegrees(48.0, 2.0),
+	}
+	// clockwise hole
+	hole := []s2.LatLng{
+		s2.LatLngFromDegrees(48.4, 2.4),
+		s2.LatLngFromDegrees(48.6, 2.4),
+		s2.LatLngFromDegrees(48.6, 2.6),
+		s2.LatLngFromDegrees(48.4, 2.6),
+	}
+	other := []s2.LatLng{
+		s2.LatLngFromDegrees(40.0, 2.0),
+		s2.LatLngFromDegrees(40.0, 3.0),
+		s2.LatLngFromDegrees(41.0, 3.0),
+	}
+
+	p, err := NewPolygon(shell, hole, other)
+	require.NoError(t, err)
+
+	require.True(t, p.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(48.2, 2.2))))
+	require.False(t, p.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(48.5, 2.5))))
+	require.True(t, p.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(40.2, 2.6))))
+	require.False(t, p.ContainsPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(45.0, 2.5))))
+
+	_, err = NewPolygon(shell[:2])
+	require.Equal(t, ErrInvalidPolygon, err)
+}
@@ -4,9 +4,14 @@ import (
 	"encoding/binary"
 	"math"
 	"time"
+
+	"github.com/golang/geo/s2"
 )
 
-const earthCircumferenceMeter = 40075017
+const (
+	earthCircumferenceMeter = 40075017
+	earthRadiusMeter        = earthCircumferenceMeter / (2 * math.Pi)
+)
 
 var (
 	// MaxGeoTime helper to query into the future
@@ -38,3 +43,13 @@ func S2RadialAreaMeters(radius float64) float64 {
 	r := (radius / earthCircumferenceMeter) * math.Pi * 2
 	return math.Pi * r * r
 }
+
+// S2DistanceMeters returns the distance in meters between a and b
+func S2DistanceMeters(a, b s2.Point) float64 {
+	return a.Distance(b).Radians() * earthRadiusMeter
+}
+
+// S2AngleMeters returns the length in meters of an angle in radians at the earth surface
+func S2AngleMeters(radians float64) float64 {
+	return radians * earthRadiusMeter
+}
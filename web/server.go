@@ -1,30 +1,70 @@
 package web
 
 import (
-	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"html/template"
+	"io"
+	"io/ioutil"
 	"mime"
+	"net"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/akhenakh/cayenne"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/gobuffalo/packr/v2"
+	"github.com/golang/geo/s2"
 	"github.com/gorilla/mux"
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	"github.com/twpayne/go-geom"
 	"github.com/twpayne/go-geom/encoding/geojson"
 
+	"github.com/akhenakh/geottn/broadcast"
+	"github.com/akhenakh/geottn/coverage"
+	"github.com/akhenakh/geottn/decoder"
+	"github.com/akhenakh/geottn/export"
 	"github.com/akhenakh/geottn/storage"
 )
 
+const (
+	maxBodySize = 1 << 20
+
+	// exportPageSize is the number of points read at once while exporting
+	exportPageSize = 500
+
+	// coveragePageSize is the number of points read at once while aggregating the coverage
+	coveragePageSize = 1000
+
+	// maxCoveragePoints is the number of points aggregated by a coverage query, the result is partial past it
+	maxCoveragePoints = 100 * coveragePageSize
+
+	// defaultCoverageWindow is the history aggregated by a coverage query without from
+	defaultCoverageWindow = 7 * 24 * time.Hour
+
+	// maxTrackPoints is the number of positions returned by a track query, the most recent ones past it
+	maxTrackPoints = 10000
+
+	// defaultTrackWindow is the history returned by a track query without from
+	defaultTrackWindow = 24 * time.Hour
+
+	// defaultCoverageLevel is the coverage cell level, about 600m wide cells
+	defaultCoverageLevel = 14
+
+	// maxCellLevel is the s2 leaf cells level
+	maxCellLevel = 30
+
+	// liveKeepAlive is the interval between SSE comments keeping idle connections open
+	liveKeepAlive = 30 * time.Second
+)
+
 var (
 	pathTpl = []string{"index.html"}
 )
@@ -36,6 +76,12 @@ type Server struct {
 	config      Config
 	FileHandler http.Handler
 	Box         *packr.Box
+
+	// Broadcaster feeds the live endpoint, disabled if nil
+	Broadcaster *broadcast.Broadcaster
+
+	// DeleteDevice removes all the data of a device, serialised with its writes, the storage Delete if nil
+	DeleteDevice func(k string) error
 }
 
 type Config struct {
@@ -120,9 +166,19 @@ func (s *Server) DataQuery(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
 
+	cursor, limit, err := pageParams(r, 100)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
-	dps, err := s.geoDB.GetAll(vars["key"], 100)
+	dps, next, err := s.geoDB.GetRangePage(vars["key"], storage.MinGeoTime, storage.MaxGeoTime, cursor, limit)
+	if err == storage.ErrInvalidCursor {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		level.Error(s.logger).Log("msg", "can't query Get", "key", vars["key"], "error", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -132,8 +188,11 @@ func (s *Server) DataQuery(w http.ResponseWriter, r *http.Request) {
 
 	res := make([]map[string]interface{}, len(dps))
 	for i, dp := range dps {
-		dec := cayenne.NewDecoder(bytes.NewBuffer(dp.Value))
-		msg, err := dec.DecodeUplink()
+		var name string
+		if dp.Meta != nil {
+			name = dp.Meta.Decoder
+		}
+		dec, err := decoder.Decode(name, dp.Value)
 		if err != nil {
 			level.Error(s.logger).Log("msg", "can't decode uplink message", "key", vars["key"], "error", err)
 			w.WriteHeader(http.StatusInternalServerError)
@@ -141,17 +200,22 @@ func (s *Server) DataQuery(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		jsresp := make(map[string]interface{})
-		for k, v := range msg.Values() {
+		for k, v := range dec.Values {
 			jsresp[k] = v
 		}
 		jsresp["device_id"] = dp.Key
 		jsresp["lat"] = dp.Lat
 		jsresp["lng"] = dp.Lng
 		jsresp["time"] = dp.Time.Format(time.RFC3339)
+		if dp.Meta != nil {
+			jsresp["meta"] = dp.Meta
+		}
 
 		res[i] = jsresp
 	}
 
+	setNextCursor(w, next)
+
 	b, err := json.Marshal(res)
 	if err != nil {
 		level.Error(s.logger).Log("msg", "can't marshal json", "key", vars["key"], "error", err)
@@ -162,10 +226,12 @@ func (s *Server) DataQuery(w http.ResponseWriter, r *http.Request) {
 	w.Write(b)
 }
 
-func (s *Server) RectQuery(w http.ResponseWriter, r *http.Request) {
+// TrackQuery returns the path of a device as a GeoJSON LineString feature,
+// in chronological order with the time of each vertex in the times property
+func (s *Server) TrackQuery(w http.ResponseWriter, r *http.Request) {
 	ctx := context.Background()
 	var serverSpan opentracing.Span
-	operationName := "/api/rect"
+	operationName := "/api/track"
 	wireContext, err := opentracing.GlobalTracer().Extract(
 		opentracing.HTTPHeaders,
 		opentracing.HTTPHeadersCarrier(r.Header))
@@ -181,22 +247,193 @@ func (s *Server) RectQuery(w http.ResponseWriter, r *http.Request) {
 	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
 
 	vars := mux.Vars(r)
-	urlat, err := strconv.ParseFloat(vars["urlat"], 64)
+
+	from, to, err := windowParams(r, defaultTrackWindow)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	dps, err := s.geoDB.GetRange(vars["key"], from, to, maxTrackPoints+1)
+	if err != nil {
+		level.Error(s.logger).Log("msg", "can't query GetRange", "key", vars["key"], "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if len(dps) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if len(dps) > maxTrackPoints {
+		dps = dps[:maxTrackPoints]
+		w.Header().Set("X-Partial-Result", "true")
+	}
+
+	// GetRange is most recent first
+	coords := make([]float64, 0, 2*len(dps))
+	times := make([]string, 0, len(dps))
+	for i := len(dps) - 1; i >= 0; i-- {
+		coords = append(coords, dps[i].Lng, dps[i].Lat)
+		times = append(times, dps[i].Time.Format(time.RFC3339))
+	}
+
+	// a LineString needs at least 2 positions
+	var g geom.T = geom.NewLineStringFlat(geom.XY, coords)
+	if len(dps) == 1 {
+		g = geom.NewPointFlat(geom.XY, coords)
+	}
+
+	f := &geojson.Feature{
+		Geometry: g,
+		Properties: map[string]interface{}{
+			"device_id": vars["key"],
+			"times":     times,
+		},
+	}
+
+	b, err := f.MarshalJSON()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
 		return
 	}
-	urlng, err := strconv.ParseFloat(vars["urlng"], 64)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+// ExportQuery streams the history of a device in the requested export format
+func (s *Server) ExportQuery(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	var serverSpan opentracing.Span
+	operationName := "/api/export"
+	wireContext, err := opentracing.GlobalTracer().Extract(
+		opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(r.Header))
+	if err != nil {
+		level.Debug(s.logger).Log("msg", "can't find a span", "error", err)
+	}
+
+	serverSpan = opentracing.StartSpan(
+		operationName,
+		ext.RPCServerOption(wireContext))
+
+	defer serverSpan.Finish()
+	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
+
+	vars := mux.Vars(r)
+	key, format := vars["key"], vars["format"]
+
+	from, to, err := timeParams(r)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment",
+		map[string]string{"filename": key + "." + format}))
+
+	enc, err := export.NewEncoder(format, w, key, s.logger)
+	if err == export.ErrUnknownFormat {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		level.Error(s.logger).Log("msg", "can't write export", "key", key, "error", err)
+		return
+	}
+
+	// the response is already started, errors can only be logged
+	var cursor []byte
+	for {
+		// tracks are drawn in order, oldest first
+		dps, next, err := s.geoDB.GetRangePageAsc(key, from, to, cursor, exportPageSize)
+		if err != nil {
+			level.Error(s.logger).Log("msg", "can't query GetRangePageAsc", "key", key, "error", err)
+			return
+		}
+		for _, dp := range dps {
+			dp := dp
+			if err := enc.Encode(&dp); err != nil {
+				level.Error(s.logger).Log("msg", "can't encode data point", "key", key, "error", err)
+				return
+			}
+		}
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+
+	if err := enc.Close(); err != nil {
+		level.Error(s.logger).Log("msg", "can't write export", "key", key, "error", err)
+	}
+}
+
+func (s *Server) DeleteQuery(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	var serverSpan opentracing.Span
+	operationName := "/api/data/delete"
+	wireContext, err := opentracing.GlobalTracer().Extract(
+		opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(r.Header))
+	if err != nil {
+		level.Debug(s.logger).Log("msg", "can't find a span", "error", err)
+	}
+
+	serverSpan = opentracing.StartSpan(
+		operationName,
+		ext.RPCServerOption(wireContext))
+
+	defer serverSpan.Finish()
+	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
+
+	vars := mux.Vars(r)
+
+	del := s.DeleteDevice
+	if del == nil {
+		del = s.geoDB.Delete
+	}
+	if err := del(vars["key"]); err != nil {
+		level.Error(s.logger).Log("msg", "can't delete", "key", vars["key"], "error", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
 		return
 	}
-	bllat, err := strconv.ParseFloat(vars["bllat"], 64)
+	level.Info(s.logger).Log("msg", "deleted device", "key", vars["key"])
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) RectQuery(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	var serverSpan opentracing.Span
+	operationName := "/api/rect"
+	wireContext, err := opentracing.GlobalTracer().Extract(
+		opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(r.Header))
+	if err != nil {
+		level.Debug(s.logger).Log("msg", "can't find a span", "error", err)
+	}
+
+	serverSpan = opentracing.StartSpan(
+		operationName,
+		ext.RPCServerOption(wireContext))
+
+	defer serverSpan.Finish()
+	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
+
+	urlat, urlng, bllat, bllng, err := rectParams(mux.Vars(r))
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
-	bllng, err := strconv.ParseFloat(vars["bllng"], 64)
+	cursor, limit, err := pageParams(r, 0)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
@@ -204,22 +441,169 @@ func (s *Server) RectQuery(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 
-	dpts, err := s.geoDB.RectSearch(urlat, urlng, bllat, bllng)
+	dpts, next, err := s.geoDB.RectSearchPage(urlat, urlng, bllat, bllng, cursor, limit)
+	if err == storage.ErrInvalidCursor {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		w.Write([]byte(err.Error()))
 		return
 	}
-	fc := geojson.FeatureCollection{}
-	for _, p := range dpts {
-		f := &geojson.Feature{}
-		f.Properties = make(map[string]interface{})
-		f.Properties["device_id"] = p.Key
-		f.Properties["ts"] = p.Time.Format(time.RFC3339)
+	fc := pointsFeatureCollection(dpts)
+	b, err := fc.MarshalJSON()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	setNextCursor(w, next)
+	w.Write(b)
+}
+
+// connKey is the request context key of the client connection
+type connKey struct{}
 
-		pg := geom.NewPointFlat(geom.XY, []float64{p.Lng, p.Lat})
-		f.Geometry = pg
-		fc.Features = append(fc.Features, f)
+// ConnContext keeps the client connection in the request context, to be set as the http.Server ConnContext,
+// it allows the live endpoint to run past the server WriteTimeout
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connKey{}, c)
+}
+
+// LiveQuery streams as Server-Sent Events the points stored inside the rect,
+// each event is a GeoJSON feature, the stream is not bound by the server WriteTimeout
+func (s *Server) LiveQuery(w http.ResponseWriter, r *http.Request) {
+	if s.Broadcaster == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	urlat, urlng, bllat, bllng, err := rectParams(mux.Vars(r))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("streaming unsupported"))
+		return
+	}
+
+	// the server sets the write deadline when reading the request, the next request sets it again
+	if c, ok := r.Context().Value(connKey{}).(net.Conn); ok {
+		if err := c.SetWriteDeadline(time.Time{}); err != nil {
+			level.Warn(s.logger).Log("msg", "can't clear the write deadline", "error", err)
+		}
+	}
+
+	rect := s2.RectFromLatLng(s2.LatLngFromDegrees(bllat, bllng))
+	rect = rect.AddPoint(s2.LatLngFromDegrees(urlat, urlng))
+	sub := s.Broadcaster.Subscribe(broadcast.Filter{Region: rect})
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(liveKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case dp := <-sub.C:
+			b, err := pointFeature(dp).MarshalJSON()
+			if err != nil {
+				level.Error(s.logger).Log("msg", "can't marshal json", "key", dp.Key, "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", b); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// CoverageQuery returns the signal of the uplinks sent from inside the rect aggregated into s2 cells,
+// as GeoJSON polygons, for one gateway or all combined
+func (s *Server) CoverageQuery(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	var serverSpan opentracing.Span
+	operationName := "/api/coverage"
+	wireContext, err := opentracing.GlobalTracer().Extract(
+		opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(r.Header))
+	if err != nil {
+		level.Debug(s.logger).Log("msg", "can't find a span", "error", err)
+	}
+
+	serverSpan = opentracing.StartSpan(
+		operationName,
+		ext.RPCServerOption(wireContext))
+
+	defer serverSpan.Finish()
+	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
+
+	urlat, urlng, bllat, bllng, err := rectParams(mux.Vars(r))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	from, to, err := windowParams(r, defaultCoverageWindow)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	cellLevel := defaultCoverageLevel
+	if l := r.URL.Query().Get("level"); l != "" {
+		cellLevel, err = strconv.Atoi(l)
+		if err != nil || cellLevel < 0 || cellLevel > maxCellLevel {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	grid := coverage.NewGrid(cellLevel, r.URL.Query().Get("gateway"))
+	var cursor []byte
+	var count int
+	for {
+		dps, next, err := s.geoDB.RectHistorySearch(urlat, urlng, bllat, bllng, from, to, cursor, coveragePageSize)
+		if err != nil {
+			level.Error(s.logger).Log("msg", "can't query RectHistorySearch", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		for i := range dps {
+			grid.Add(&dps[i])
+		}
+		if next == nil {
+			break
+		}
+		count += len(dps)
+		if count >= maxCoveragePoints {
+			w.Header().Set("X-Partial-Result", "true")
+			break
+		}
+		cursor = next
+	}
+
+	fc := &geojson.FeatureCollection{}
+	for _, c := range grid.Cells() {
+		fc.Features = append(fc.Features, cellFeature(c))
 	}
 	b, err := fc.MarshalJSON()
 	if err != nil {
@@ -228,6 +612,69 @@ func (s *Server) RectQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(b)
+}
+
+func (s *Server) PolygonQuery(w http.ResponseWriter, r *http.Request) {
+	ctx := context.Background()
+	var serverSpan opentracing.Span
+	operationName := "/api/polygon"
+	wireContext, err := opentracing.GlobalTracer().Extract(
+		opentracing.HTTPHeaders,
+		opentracing.HTTPHeadersCarrier(r.Header))
+	if err != nil {
+		level.Debug(s.logger).Log("msg", "can't find a span", "error", err)
+	}
+
+	serverSpan = opentracing.StartSpan(
+		operationName,
+		ext.RPCServerOption(wireContext))
+
+	defer serverSpan.Finish()
+	ctx = opentracing.ContextWithSpan(ctx, serverSpan)
+
+	cursor, limit, err := pageParams(r, 0)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	b, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	p, err := geoJSONPolygon(b)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	dpts, next, err := s.geoDB.PolygonSearch(p, cursor, limit)
+	if err == storage.ErrInvalidCursor {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	fc := pointsFeatureCollection(dpts)
+	b, err = fc.MarshalJSON()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	setNextCursor(w, next)
 	w.Write(b)
 }
 
@@ -274,6 +721,187 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	tmplt.Execute(w, p)
 }
 
+// pointsFeatureCollection returns a GeoJSON feature collection of points
+func pointsFeatureCollection(dpts []storage.DataPoint) *geojson.FeatureCollection {
+	fc := &geojson.FeatureCollection{}
+	for _, p := range dpts {
+		fc.Features = append(fc.Features, pointFeature(p))
+	}
+	return fc
+}
+
+// pointFeature returns a GeoJSON point feature for p
+func pointFeature(p storage.DataPoint) *geojson.Feature {
+	f := &geojson.Feature{}
+	f.Properties = make(map[string]interface{})
+	f.Properties["device_id"] = p.Key
+	f.Properties["ts"] = p.Time.Format(time.RFC3339)
+	f.Geometry = geom.NewPointFlat(geom.XY, []float64{p.Lng, p.Lat})
+	return f
+}
+
+// cellFeature returns a GeoJSON polygon feature for a coverage cell
+func cellFeature(c coverage.Cell) *geojson.Feature {
+	cell := s2.CellFromCellID(c.ID)
+	coords := make([]float64, 0, 10)
+	for i := 0; i < 5; i++ {
+		ll := s2.LatLngFromPoint(cell.Vertex(i % 4))
+		coords = append(coords, ll.Lng.Degrees(), ll.Lat.Degrees())
+	}
+
+	return &geojson.Feature{
+		Geometry: geom.NewPolygonFlat(geom.XY, coords, []int{len(coords)}),
+		Properties: map[string]interface{}{
+			"cell":     c.ID.ToToken(),
+			"count":    c.Count,
+			"rssi":     c.MeanRSSI,
+			"rssi_min": c.MinRSSI,
+			"rssi_max": c.MaxRSSI,
+			"snr":      c.MeanSNR,
+			"gateways": c.Gateways,
+		},
+	}
+}
+
+// geoJSONPolygon reads a GeoJSON Polygon or MultiPolygon, geometry or feature
+func geoJSONPolygon(b []byte) (*s2.Polygon, error) {
+	var typ struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(b, &typ); err != nil {
+		return nil, err
+	}
+
+	var g geom.T
+	if typ.Type == "Feature" {
+		f := &geojson.Feature{}
+		if err := f.UnmarshalJSON(b); err != nil {
+			return nil, err
+		}
+		g = f.Geometry
+	} else {
+		if err := geojson.Unmarshal(b, &g); err != nil {
+			return nil, err
+		}
+	}
+
+	var polygons []*geom.Polygon
+	switch gt := g.(type) {
+	case *geom.Polygon:
+		polygons = append(polygons, gt)
+	case *geom.MultiPolygon:
+		for i := 0; i < gt.NumPolygons(); i++ {
+			polygons = append(polygons, gt.Polygon(i))
+		}
+	default:
+		return nil, errors.New("expecting a Polygon or a MultiPolygon")
+	}
+
+	var rings [][]s2.LatLng
+	for _, p := range polygons {
+		for i := 0; i < p.NumLinearRings(); i++ {
+			coords := p.LinearRing(i).Coords()
+			ring := make([]s2.LatLng, len(coords))
+			for j, c := range coords {
+				ring[j] = s2.LatLngFromDegrees(c.Y(), c.X())
+			}
+			rings = append(rings, ring)
+		}
+	}
+
+	return storage.NewPolygon(rings...)
+}
+
+// rectParams parses the rect path variables
+func rectParams(vars map[string]string) (urlat, urlng, bllat, bllng float64, err error) {
+	if urlat, err = strconv.ParseFloat(vars["urlat"], 64); err != nil {
+		return
+	}
+	if urlng, err = strconv.ParseFloat(vars["urlng"], 64); err != nil {
+		return
+	}
+	if bllat, err = strconv.ParseFloat(vars["bllat"], 64); err != nil {
+		return
+	}
+	bllng, err = strconv.ParseFloat(vars["bllng"], 64)
+	return
+}
+
+// timeParams returns the optional from and to RFC3339 query parameters,
+// defaulting to the whole time range
+func timeParams(r *http.Request) (time.Time, time.Time, error) {
+	from, to := storage.MinGeoTime, storage.MaxGeoTime
+
+	q := r.URL.Query()
+	if v := q.Get("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return from, to, err
+		}
+		from = t
+	}
+
+	if v := q.Get("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return from, to, err
+		}
+		to = t
+	}
+
+	return from, to, nil
+}
+
+// windowParams returns the from and to query parameters like timeParams,
+// without from the window preceding to or now is returned
+func windowParams(r *http.Request, window time.Duration) (time.Time, time.Time, error) {
+	from, to, err := timeParams(r)
+	if err != nil {
+		return from, to, err
+	}
+	if r.URL.Query().Get("from") == "" {
+		end := time.Now()
+		if to.Before(end) {
+			end = to
+		}
+		from = end.Add(-window)
+	}
+	return from, to, nil
+}
+
+// pageParams returns the optional cursor and limit query parameters
+func pageParams(r *http.Request, defaultLimit int) ([]byte, int, error) {
+	var cursor []byte
+	limit := defaultLimit
+
+	q := r.URL.Query()
+	if c := q.Get("cursor"); c != "" {
+		b, err := base64.RawURLEncoding.DecodeString(c)
+		if err != nil {
+			return nil, 0, err
+		}
+		cursor = b
+	}
+
+	if l := q.Get("limit"); l != "" {
+		v, err := strconv.Atoi(l)
+		if err != nil {
+			return nil, 0, err
+		}
+		limit = v
+	}
+
+	return cursor, limit, nil
+}
+
+// setNextCursor exposes the cursor to query the next page in the X-Next-Cursor header
+func setNextCursor(w http.ResponseWriter, next []byte) {
+	if next == nil {
+		return
+	}
+	w.Header().Set("X-Next-Cursor", base64.RawURLEncoding.EncodeToString(next))
+}
+
 func isTpl(path string) bool {
 	for _, p := range pathTpl {
 		if p == path {
@@ -0,0 +1,152 @@
+package web
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/dgraph-io/badger/v2"
+	"github.com/go-kit/kit/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+	"github.com/twpayne/go-geom/encoding/geojson"
+
+	"github.com/akhenakh/geottn/broadcast"
+	"github.com/akhenakh/geottn/storage"
+	badgeridx "github.com/akhenakh/geottn/storage/badger"
+)
+
+func TestExportQueryOldestFirst(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bdb, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	require.NoError(t, err)
+	defer bdb.Close()
+
+	idx := &badgeridx.Indexer{DB: bdb}
+	s := NewServer("test", log.NewNopLogger(), idx, Config{})
+
+	ts := time.Date(2019, 11, 22, 10, 0, 0, 0, time.UTC)
+	for _, i := range []int{2, 0, 3, 1} {
+		err := idx.Store("KEY", nil, 48.8, 2.2+float64(i)*0.001, ts.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/export/{key}/{format}", s.ExportQuery)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/export/KEY/csv", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	rows, err := csv.NewReader(rec.Body).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	for i, row := range rows[1:] {
+		require.Equal(t, ts.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), row[1])
+	}
+}
+
+func TestCoverageQueryDefaultWindow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bdb, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	require.NoError(t, err)
+	defer bdb.Close()
+
+	idx := &badgeridx.Indexer{DB: bdb}
+	s := NewServer("test", log.NewNopLogger(), idx, Config{})
+
+	now := time.Now().UTC()
+	old := now.Add(-10 * 24 * time.Hour)
+	meta := &storage.Metadata{Gateways: []storage.GatewayMetadata{{ID: "gw1", RSSI: -80, SNR: 7}}}
+	tx := idx.Begin()
+	require.NoError(t, idx.StoreTx(tx, "KEY", nil, 48.8, 2.2, old))
+	require.NoError(t, idx.StoreMetaTx(tx, "KEY", 48.8, 2.2, old, meta))
+	require.NoError(t, idx.StoreTx(tx, "KEY", nil, 48.85, 2.3, now))
+	require.NoError(t, idx.StoreMetaTx(tx, "KEY", 48.85, 2.3, now, meta))
+	require.NoError(t, tx.Commit())
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/coverage/{urlat}/{urlng}/{bllat}/{bllng}", s.CoverageQuery)
+	cells := func(query string) *geojson.FeatureCollection {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/coverage/49/3/48/2"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var fc geojson.FeatureCollection
+		require.NoError(t, fc.UnmarshalJSON(rec.Body.Bytes()))
+		return &fc
+	}
+
+	// only the recent point without from
+	require.Len(t, cells("").Features, 1)
+	require.Len(t, cells("?from="+old.Add(-time.Hour).Format(time.RFC3339)).Features, 2)
+}
+
+func TestTrackQueryDefaultWindow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "badger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	bdb, err := badger.Open(badger.DefaultOptions(dir).WithLogger(nil))
+	require.NoError(t, err)
+	defer bdb.Close()
+
+	idx := &badgeridx.Indexer{DB: bdb}
+	s := NewServer("test", log.NewNopLogger(), idx, Config{})
+
+	now := time.Now().UTC()
+	old := now.Add(-2 * 24 * time.Hour)
+	require.NoError(t, idx.Store("KEY", nil, 48.8, 2.2, old))
+	require.NoError(t, idx.Store("KEY", nil, 48.81, 2.21, now.Add(-time.Hour)))
+	require.NoError(t, idx.Store("KEY", nil, 48.82, 2.22, now))
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/track/{key}", s.TrackQuery)
+	track := func(query string) *geojson.Feature {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/track/KEY"+query, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var f geojson.Feature
+		require.NoError(t, f.UnmarshalJSON(rec.Body.Bytes()))
+		return &f
+	}
+
+	// only the recent points without from
+	require.Len(t, track("").Properties["times"], 2)
+	require.Len(t, track("?from="+old.Add(-time.Hour).Format(time.RFC3339)).Properties["times"], 3)
+}
+
+func TestLiveQueryWriteTimeout(t *testing.T) {
+	s := NewServer("test", log.NewNopLogger(), nil, Config{})
+	s.Broadcaster = broadcast.New(10)
+
+	r := mux.NewRouter()
+	r.HandleFunc("/api/live/{urlat}/{urlng}/{bllat}/{bllng}", s.LiveQuery)
+	ts := httptest.NewUnstartedServer(r)
+	ts.Config.WriteTimeout = 100 * time.Millisecond
+	ts.Config.ConnContext = ConnContext
+	ts.Start()
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/api/live/49/3/48/2")
+	require.NoError(t, err)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	// the stream outlives the server WriteTimeout
+	time.Sleep(300 * time.Millisecond)
+	s.Broadcaster.Publish(storage.DataPoint{Key: "KEY", Lat: 48.8, Lng: 2.2, Time: time.Now()})
+
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	require.NoError(t, err)
+	require.Contains(t, line, `"device_id":"KEY"`)
+}